@@ -0,0 +1,185 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AliasPolicy sets what happens when a change modifies a node that is
+// shared through a yaml anchor. Aliases are expanded on Read, so such a
+// change does not reach the other paths that share the node
+type AliasPolicy int
+
+const (
+	// AliasWarn prints a warning and applies the change
+	AliasWarn AliasPolicy = iota
+	// AliasError returns an error and does not apply the change
+	AliasError
+	// AliasIgnore applies the change silently
+	AliasIgnore
+)
+
+// SetAliasPolicy for configuring what happens when an Upsert modifies
+// an anchored node, an alias or a merged key. The default is AliasWarn
+func (s *Storage) SetAliasPolicy(p AliasPolicy) *Storage {
+	s.aliasPolicy = p
+	return s
+}
+
+// ResolvePath returns the path that defines the value of k in the
+// Active Document. Paths that go through an alias or a key that comes
+// from a merge key (<<) resolve to the anchored node, e.g. with
+// "dev: {<<: *defaults}" the path "dev.adapter" resolves to the path
+// of the defaults anchor followed by "adapter". Anchors are known as
+// long as the local file or the imported documents have them
+func (s *Storage) ResolvePath(k string) (string, error) {
+	if _, err := s.GetPathRaw(k); err != nil {
+		return "", wrapErr(err)
+	}
+
+	l := s.layouts[s.ids[s.GetAD()]]
+
+	var p string
+	for _, j := range strings.Split(k, ".") {
+		p = l.resolve(joinPath(p, j))
+	}
+
+	return p, nil
+}
+
+// checkAliased applies the alias policy if a change on path k of
+// the i'th document modifies a node that is shared through an anchor
+func (s *Storage) checkAliased(i int, k string) error {
+	if s.aliasPolicy == AliasIgnore || i >= len(s.ids) {
+		return nil
+	}
+	l := s.layouts[s.ids[i]]
+
+	var p string
+	for _, j := range strings.Split(k, ".") {
+		p = joinPath(p, j)
+		a, shared := l.shared(p)
+		if !shared {
+			continue
+		}
+		if s.aliasPolicy == AliasError {
+			return wrapErr(aliasedNode, k, a)
+		}
+		issueWarning(aliasedNodeWarn, k, a)
+		return nil
+	}
+
+	return nil
+}
+
+// shared returns the anchor that the node at p is shared through
+func (l layout) shared(p string) (string, bool) {
+	if a, exists := l.aliases[p]; exists {
+		return a, true
+	}
+	if a, exists := l.merged[p]; exists {
+		return a, true
+	}
+	if a, exists := l.anchors[p]; exists && l.used[a] {
+		return a, true
+	}
+	return "", false
+}
+
+// resolve follows aliases and merged keys from p to the path of
+// the node that defines its value
+func (l layout) resolve(p string) string {
+	for n := 0; n <= len(l.paths); n++ {
+		if a, exists := l.aliases[p]; exists && l.paths[a] != "" {
+			p = l.paths[a]
+			continue
+		}
+		if a, exists := l.merged[p]; exists && l.paths[a] != "" {
+			p = joinPath(l.paths[a], p[strings.LastIndex(p, ".")+1:])
+			continue
+		}
+		break
+	}
+	return p
+}
+
+// mayHaveAnchors reports if a yaml stream can have anchors, aliases
+// or merge keys. Streams without them do not need a layout for
+// checking aliased nodes
+func mayHaveAnchors(b []byte) bool {
+	return bytes.ContainsAny(b, "&*") || bytes.Contains(b, []byte("<<"))
+}
+
+// alias returns the anchor of the alias node at p if the anchor has
+// been added and v still matches its value
+func (b *yamlBuilder) alias(p string, v interface{}) string {
+	if !b.style.KeepAnchors {
+		return ""
+	}
+
+	a, exists := b.layout.aliases[p]
+	if !exists {
+		return ""
+	}
+	if d, defined := b.defined[a]; defined && reflect.DeepEqual(d, v) {
+		return a
+	}
+	return ""
+}
+
+// anchor returns the anchor of the node at p and records v as its value
+func (b *yamlBuilder) anchor(p string, v interface{}) string {
+	if !b.style.KeepAnchors {
+		return ""
+	}
+
+	a, exists := b.layout.anchors[p]
+	if exists {
+		b.defined[a] = v
+	}
+	return a
+}
+
+// merge returns the keys of the map m at p that are left to write and
+// the anchors of its merge key. Merged keys are dropped if they still
+// match their anchor. If m lacks a merged key or an anchor has not been
+// added, there is no merge key and all keys are written
+func (b *yamlBuilder) merge(m map[interface{}]interface{}, p string, keys []interface{}) ([]interface{}, []string) {
+	anchors := b.layout.merges[p]
+	if !b.style.KeepAnchors || len(anchors) == 0 {
+		return keys, nil
+	}
+
+	// Earlier anchors take precedence
+	merged := make(map[interface{}]interface{})
+	for _, j := range anchors {
+		src, isMap := b.defined[j].(map[interface{}]interface{})
+		if !isMap {
+			return keys, nil
+		}
+		for k, v := range src {
+			if _, exists := merged[k]; !exists {
+				merged[k] = v
+			}
+		}
+	}
+
+	for k := range merged {
+		if _, exists := m[k]; !exists {
+			return keys, nil
+		}
+	}
+
+	rest := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		_, fromMerge := b.layout.merged[joinPath(p, fmt.Sprint(k))]
+		if fromMerge && reflect.DeepEqual(m[k], merged[k]) {
+			continue
+		}
+		rest = append(rest, k)
+	}
+
+	return rest, anchors
+}
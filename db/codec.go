@@ -0,0 +1,233 @@
+package db
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Codec decodes and encodes the documents of a file format.
+// Decoded documents use the same types as the yaml decoder, that is
+// map[interface{}]interface{}, []interface{} and scalars
+type Codec interface {
+	Decode(r io.Reader) ([]interface{}, error)
+	Encode(w io.Writer, docs []interface{}) error
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		".yaml":       YAMLCodec{},
+		".yml":        YAMLCodec{},
+		".json":       JSONCodec{},
+		".ndjson":     JSONCodec{NDJSON: true},
+		".jsonl":      JSONCodec{NDJSON: true},
+		".toml":       TOMLCodec{},
+		".properties": PropertiesCodec{},
+		".env":        EnvCodec{},
+	}
+)
+
+// RegisterCodec registers a Codec for the given file extension
+// (e.g. ".hcl"). Registering an extension again replaces its Codec
+func RegisterCodec(ext string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[strings.ToLower(ext)] = c
+}
+
+// CodecFor returns the Codec that is registered for the extension
+// of p. Files with an unknown extension use the YAMLCodec
+func CodecFor(p string) Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	if c, exists := codecs[strings.ToLower(filepath.Ext(p))]; exists {
+		return c
+	}
+	return YAMLCodec{}
+}
+
+// SetCodec for configuring the Codec that is used to read and write
+// the local file, instead of the one registered for its extension
+func (s *Storage) SetCodec(c Codec) *Storage {
+	s.codec = c
+	return s
+}
+
+// fileCodec returns the Codec of the local file
+func (s *Storage) fileCodec() Codec {
+	if s.codec != nil {
+		return s.codec
+	}
+	return CodecFor(s.Path)
+}
+
+// readBufferWith decodes all documents from r into the buffer array.
+// If c can report the layout of the documents and the Storage keeps it
+// or checks for aliased nodes, the layouts are added to the layout buffer
+func (s *Storage) readBufferWith(r io.Reader, c Codec) error {
+	s.UnsetBufferArray()
+
+	lc, hasLayout := c.(layoutCodec)
+	if !hasLayout {
+		docs, err := c.Decode(r)
+		if err != nil {
+			return wrapErr(err)
+		}
+		for _, j := range docs {
+			s.PushBuffer(j)
+		}
+		return nil
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	docs, err := c.Decode(bytes.NewReader(b))
+	if err != nil {
+		return wrapErr(err)
+	}
+	for _, j := range docs {
+		s.PushBuffer(j)
+	}
+
+	if !s.keepsLayout() && (s.aliasPolicy == AliasIgnore || !mayHaveAnchors(b)) {
+		return nil
+	}
+
+	layouts, err := lc.decodeLayouts(b)
+	if err == nil && len(layouts) == len(docs) {
+		s.layoutBuffer = layouts
+	}
+
+	return nil
+}
+
+// writeDocsWith encodes the documents with the given indexes to w.
+// Null documents are skipped unless KeepEmptyDocs is set
+func (s *Storage) writeDocsWith(w io.Writer, idx []int, c Codec) error {
+	docs := make([]interface{}, 0, len(idx))
+	layouts := make([]layout, 0, len(idx))
+	for _, i := range idx {
+		if s.data[i] == nil && !s.keepEmpty {
+			continue
+		}
+		docs = append(docs, s.data[i])
+		layouts = append(layouts, s.layouts[s.ids[i]])
+	}
+
+	if lc, hasLayout := c.(layoutCodec); hasLayout && lc.keepsLayout() {
+		var buf bytes.Buffer
+		if err := lc.encodeLayouts(&buf, docs, layouts); err != nil {
+			return wrapErr(err)
+		}
+		_, err := w.Write(buf.Bytes())
+		return wrapErr(err)
+	}
+
+	return wrapErr(c.Encode(w, docs))
+}
+
+// keepsLayout reports if the Codec of the local file writes map keys
+// in the order they were read or keeps anchors and aliases
+func (s *Storage) keepsLayout() bool {
+	lc, hasLayout := s.fileCodec().(layoutCodec)
+	return hasLayout && lc.keepsLayout()
+}
+
+// YAMLCodec reads and writes multi document yaml streams.
+// Style configures the output
+type YAMLCodec struct {
+	Style YAMLStyle
+}
+
+// Decode for decoding all yaml documents from r
+func (YAMLCodec) Decode(r io.Reader) ([]interface{}, error) {
+	docs := make([]interface{}, 0)
+
+	dec := yaml.NewDecoder(r)
+	for {
+		var data interface{}
+		err := dec.Decode(&data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		docs = append(docs, data)
+	}
+
+	return docs, nil
+}
+
+// Encode for encoding docs to w as a multi document yaml stream
+func (c YAMLCodec) Encode(w io.Writer, docs []interface{}) error {
+	if c.Style != (YAMLStyle{}) {
+		var buf bytes.Buffer
+		if err := c.encodeLayouts(&buf, docs, nil); err != nil {
+			return wrapErr(err)
+		}
+		_, err := w.Write(buf.Bytes())
+		return wrapErr(err)
+	}
+
+	enc := yaml.NewEncoder(w)
+
+	for _, j := range docs {
+		if err := enc.Encode(j); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	return wrapErr(enc.Close())
+}
+
+// singleDoc returns the only document of docs. Formats that can not
+// hold several documents use it on Encode
+func singleDoc(f string, docs []interface{}) (interface{}, error) {
+	if len(docs) != 1 {
+		return nil, wrapErr(singleDocFormat, f, len(docs))
+	}
+	return docs[0], nil
+}
+
+// fromStringKeys converts decoded objects to the types that the yaml
+// decoder produces. Maps get interface keys, int64 numbers become ints
+// and arrays of maps become arrays of interfaces
+func fromStringKeys(o interface{}) interface{} {
+	switch obj := o.(type) {
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(obj))
+		for k, v := range obj {
+			m[k] = fromStringKeys(v)
+		}
+		return m
+	case []map[string]interface{}:
+		a := make([]interface{}, len(obj))
+		for i, v := range obj {
+			a[i] = fromStringKeys(v)
+		}
+		return a
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for i, v := range obj {
+			a[i] = fromStringKeys(v)
+		}
+		return a
+	case int64:
+		if int64(int(obj)) == obj {
+			return int(obj)
+		}
+	}
+	return o
+}
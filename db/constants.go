@@ -20,24 +20,44 @@ const (
 
 // Informational Error constants. Used during a return err
 const (
-	notAMap         = "target object is not a map"
-	notArrayObj     = "received a non array object but expected []interface{}"
-	keyDoesNotExist = "the given key [%s] does not exist"
-	fileNotExist    = "the given file [%s] does not exist"
-	dictNotFile     = "can not create file [%s], a directory exists with that name"
-	notAnIndex      = "object (%s) is not an index. Index example: some.path.[someInteger].someKey"
-	arrayOutOfRange = "index value (%s) is bigger than the length (%s) of the array to be indexed"
-	invalidKeyPath  = "the key||path [%s] that was given is not valid"
-	emptyKey        = "path [%s] contains an empty key"
-	libOutOfIndex   = "lib out of index"
-	docNotExists    = "doc [%s] does not exist in lib"
-	fieldNotString  = "[%s] with value [%s] is not a string"
-	notAType        = "value is not a %s"
+	notAMap           = "target object is not a map"
+	notArrayObj       = "received a non array object but expected []interface{}"
+	keyDoesNotExist   = "the given key [%s] does not exist"
+	fileNotExist      = "the given file [%s] does not exist"
+	dictNotFile       = "can not create file [%s], a directory exists with that name"
+	notAnIndex        = "object (%s) is not an index. Index example: some.path.[someInteger].someKey"
+	arrayOutOfRange   = "index value (%s) is bigger than the length (%s) of the array to be indexed"
+	invalidKeyPath    = "the key||path [%s] that was given is not valid"
+	emptyKey          = "path [%s] contains an empty key"
+	libOutOfIndex     = "lib out of index"
+	docNotExists      = "doc [%s] does not exist in lib"
+	docExists         = "doc [%s] already exists in lib"
+	fieldNotString    = "[%s] with value [%v] is not a string"
+	invalidTemplate   = "template [%s] must have {{.path}} placeholders only"
+	notAType          = "value is not a %s"
+	notANumber        = "value [%v] is not a number"
+	typeMismatch      = "[%s] with value [%v] can not be converted to %s"
+	schemaViolation   = "schema validation failed: %s"
+	kubeNoSchema      = "no schema found for apiVersion [%s] and kind [%s]"
+	invalidSelector   = "selector [%s] is not valid"
+	noDocTarget       = "doc (%d) has no origin file and no name"
+	outsideDir        = "path [%s] is outside of [%s]"
+	singleDocFormat   = "%s files hold a single document, got %d documents"
+	notAScalar        = "[%s] with value [%v] is not a scalar"
+	invalidLine       = "line %d is not valid: %v"
+	unterminatedQuote = "value [%s] has an unterminated quote"
+	aliasedNode       = "path [%s] is shared through anchor [%s]"
+	invalidReference  = "reference [%s] is not valid"
+	envNotSet         = "environment variable [%s] is not set"
+	referenceCycle    = "reference [%s] refers to itself"
+	noLayer           = "layer (%d) does not exist"
+	noFile            = "storage has no local file to write to"
 )
 
 // Warnings
 const (
 	deprecatedFeature = "Warn: Deprecated is [%s]. Will be replaced by [%s] in the future"
+	aliasedNodeWarn   = "Warn: Path [%s] is shared through anchor [%s]. Other paths that share it are not changed"
 )
 
 func issueWarning(s string, o ...interface{}) {
@@ -1,10 +1,23 @@
 package db
 
 import (
+	"math"
+	"strconv"
+	"time"
+
 	v1 "github.com/ulfox/dby/cache/v1"
 	"gopkg.in/yaml.v2"
 )
 
+// timeFormats are the timestamp formats that GetTime can parse.
+// These are the same formats that yaml uses for timestamps
+var timeFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
 // AssertData is used to for converting interface objects to
 // map of interfaces or array of interfaces
 type AssertData struct {
@@ -140,7 +153,11 @@ func (a *AssertData) Key(k string) *AssertData {
 		return a.setErr(wrapErr(notAMap))
 	}
 
-	a.cache.V1(a.cache.V1().(map[interface{}]interface{})[k])
+	v, exists := a.cache.V1().(map[interface{}]interface{})[k]
+	if !exists {
+		return a.setErr(wrapErr(keyDoesNotExist, k))
+	}
+	a.cache.V1(v)
 
 	return a
 }
@@ -155,7 +172,320 @@ func (a *AssertData) Index(i int) *AssertData {
 	if !isArray {
 		return a.setErr(wrapErr(notArrayObj))
 	}
+	if i < 0 || i > len(a.cache.V1().([]interface{}))-1 {
+		return a.setErr(
+			wrapErr(
+				arrayOutOfRange,
+				strconv.Itoa(i),
+				strconv.Itoa(len(a.cache.V1().([]interface{}))-1),
+			),
+		)
+	}
 	a.cache.V1(a.cache.V1().([]interface{})[i])
 
 	return a
 }
+
+// Decode for decoding the input into the value pointed by out. Struct
+// fields are matched using their yaml tags
+func (a *AssertData) Decode(out interface{}) error {
+	if a.GetError() != nil {
+		return a.GetError()
+	}
+
+	a.cache.E(a.cache.BE(yaml.Marshal(a.cache.V1())))
+	if a.GetError() != nil {
+		return a.GetError()
+	}
+
+	a.cache.E(yaml.Unmarshal(a.cache.B(), out))
+	return a.GetError()
+}
+
+// GetInt64 asserts the input as int64. Int values are
+// converted to int64
+func (a *AssertData) GetInt64() (int64, error) {
+	if a.GetError() != nil {
+		return 0, a.GetError()
+	}
+
+	switch i := a.cache.V1().(type) {
+	case int:
+		return int64(i), nil
+	case int64:
+		return i, nil
+	case uint64:
+		if i <= math.MaxInt64 {
+			return int64(i), nil
+		}
+	}
+
+	a.setErr(wrapErr(notAType, "int64"))
+	return 0, a.GetError()
+}
+
+// GetFloat asserts the input as float64. Int values are
+// converted to float64
+func (a *AssertData) GetFloat() (float64, error) {
+	if a.GetError() != nil {
+		return 0, a.GetError()
+	}
+
+	switch f := a.cache.V1().(type) {
+	case float64:
+		return f, nil
+	case int:
+		return float64(f), nil
+	case int64:
+		return float64(f), nil
+	case uint64:
+		return float64(f), nil
+	}
+
+	a.setErr(wrapErr(notAType, "float64"))
+	return 0, a.GetError()
+}
+
+// GetBool asserts the input as bool
+func (a *AssertData) GetBool() (bool, error) {
+	if a.GetError() != nil {
+		return false, a.GetError()
+	}
+
+	b, isBool := a.cache.V1().(bool)
+	if !isBool {
+		a.setErr(wrapErr(notAType, "bool"))
+		return false, a.GetError()
+	}
+
+	return b, nil
+}
+
+// GetDuration parses the input as time.Duration. The input
+// is expected to be a string in the form of "1h5m10s"
+func (a *AssertData) GetDuration() (time.Duration, error) {
+	if a.GetError() != nil {
+		return 0, a.GetError()
+	}
+
+	s, isString := a.cache.V1().(string)
+	if !isString {
+		a.setErr(wrapErr(notAType, "duration"))
+		return 0, a.GetError()
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		a.setErr(wrapErr(err))
+		return 0, a.GetError()
+	}
+
+	return d, nil
+}
+
+// GetTime parses the input as time.Time. The input is expected
+// to be a yaml timestamp, e.g. 2001-12-14t21:59:43.10-05:00 or 2002-12-14
+func (a *AssertData) GetTime() (time.Time, error) {
+	if a.GetError() != nil {
+		return time.Time{}, a.GetError()
+	}
+
+	switch t := a.cache.V1().(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		for _, j := range timeFormats {
+			if v, err := time.Parse(j, t); err == nil {
+				return v, nil
+			}
+		}
+	}
+
+	a.setErr(wrapErr(notAType, "timestamp"))
+	return time.Time{}, a.GetError()
+}
+
+// GetMapInterface for converting a map[interface{}]interface{} into a
+// map[string]interface{}. Nested maps are converted as well
+func (a *AssertData) GetMapInterface() (map[string]interface{}, error) {
+	if a.GetError() != nil {
+		return nil, a.GetError()
+	}
+
+	if getObjectType(a.cache.V1()) != mapObj {
+		a.setErr(wrapErr(notAMap))
+		return nil, a.GetError()
+	}
+
+	return stringKeys(a.cache.V1()).(map[string]interface{}), nil
+}
+
+// GetArrayMap for converting a []interface{} into a []map[string]interface{}.
+// All items of the array must be maps
+func (a *AssertData) GetArrayMap() ([]map[string]interface{}, error) {
+	if a.GetError() != nil {
+		return nil, a.GetError()
+	}
+
+	array, isArray := a.cache.V1().([]interface{})
+	if !isArray {
+		a.setErr(wrapErr(notArrayObj))
+		return nil, a.GetError()
+	}
+
+	m := make([]map[string]interface{}, 0, len(array))
+	for _, j := range array {
+		if getObjectType(j) != mapObj {
+			a.setErr(wrapErr(notAMap))
+			return nil, a.GetError()
+		}
+		m = append(m, stringKeys(j).(map[string]interface{}))
+	}
+
+	return m, nil
+}
+
+// GetArrayInt for converting a []interface{} to []int
+func (a *AssertData) GetArrayInt() ([]int, error) {
+	if a.GetError() != nil {
+		return nil, a.GetError()
+	}
+
+	array, isArray := a.cache.V1().([]interface{})
+	if !isArray {
+		a.setErr(wrapErr(notArrayObj))
+		return nil, a.GetError()
+	}
+
+	a.i1 = make([]int, 0, len(array))
+	for _, j := range array {
+		i, isInt := j.(int)
+		if !isInt {
+			a.setErr(wrapErr(notAType, "int"))
+			return nil, a.GetError()
+		}
+		a.i1 = append(a.i1, i)
+	}
+
+	return a.i1, nil
+}
+
+// GetStringOrDefault works like GetString but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetStringOrDefault(d string) string {
+	v, err := a.GetString()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetIntOrDefault works like GetInt but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetIntOrDefault(d int) int {
+	v, err := a.GetInt()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetInt64OrDefault works like GetInt64 but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetInt64OrDefault(d int64) int64 {
+	v, err := a.GetInt64()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetFloatOrDefault works like GetFloat but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetFloatOrDefault(d float64) float64 {
+	v, err := a.GetFloat()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetBoolOrDefault works like GetBool but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetBoolOrDefault(d bool) bool {
+	v, err := a.GetBool()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetDurationOrDefault works like GetDuration but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetDurationOrDefault(d time.Duration) time.Duration {
+	v, err := a.GetDuration()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetTimeOrDefault works like GetTime but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetTimeOrDefault(d time.Time) time.Time {
+	v, err := a.GetTime()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetMapOrDefault works like GetMap but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetMapOrDefault(d map[string]string) map[string]string {
+	v, err := a.GetMap()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetMapInterfaceOrDefault works like GetMapInterface but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetMapInterfaceOrDefault(d map[string]interface{}) map[string]interface{} {
+	v, err := a.GetMapInterface()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetArrayOrDefault works like GetArray but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetArrayOrDefault(d []string) []string {
+	v, err := a.GetArray()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetArrayMapOrDefault works like GetArrayMap but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetArrayMapOrDefault(d []map[string]interface{}) []map[string]interface{} {
+	v, err := a.GetArrayMap()
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+// GetArrayIntOrDefault works like GetArrayInt but returns d on error.
+// The error is kept and can be read with GetError
+func (a *AssertData) GetArrayIntOrDefault(d []int) []int {
+	v, err := a.GetArrayInt()
+	if err != nil {
+		return d
+	}
+	return v
+}
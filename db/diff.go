@@ -0,0 +1,181 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// ChangeOp is the kind of a Change
+type ChangeOp string
+
+const (
+	// OpAdded is a path that exists only in the new value
+	OpAdded ChangeOp = "added"
+	// OpRemoved is a path that exists only in the old value
+	OpRemoved ChangeOp = "removed"
+	// OpChanged is a path whose value is different
+	OpChanged ChangeOp = "changed"
+)
+
+// Change is a difference between two values at a path. Old is nil
+// for added paths and New is nil for removed paths
+type Change struct {
+	Path string
+	Op   ChangeOp
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff returns the changes from a to b. Maps are compared key by key
+// and arrays index by index, e.g. a changed array item is reported at
+// "some.path.[1]". Changes are sorted by path within each map. A change
+// of the whole value has an empty path
+func Diff(a, b interface{}) []Change {
+	if n, err := normalize(a); err == nil {
+		a = n
+	}
+	if n, err := normalize(b); err == nil {
+		b = n
+	}
+
+	changes := make([]Change, 0)
+	diffValues("", a, b, &changes)
+	return changes
+}
+
+func diffValues(p string, a, b interface{}, changes *[]Change) {
+	am, aIsMap := a.(map[interface{}]interface{})
+	bm, bIsMap := b.(map[interface{}]interface{})
+	if aIsMap && bIsMap {
+		diffMaps(p, am, bm, changes)
+		return
+	}
+
+	aa, aIsArray := a.([]interface{})
+	ba, bIsArray := b.([]interface{})
+	if aIsArray && bIsArray {
+		for i := 0; i < len(aa) || i < len(ba); i++ {
+			ip := joinPath(p, "["+strconv.Itoa(i)+"]")
+			switch {
+			case i >= len(ba):
+				*changes = append(*changes, Change{Path: ip, Op: OpRemoved, Old: aa[i]})
+			case i >= len(aa):
+				*changes = append(*changes, Change{Path: ip, Op: OpAdded, New: ba[i]})
+			default:
+				diffValues(ip, aa[i], ba[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: p, Op: OpChanged, Old: a, New: b})
+	}
+}
+
+func diffMaps(p string, a, b map[interface{}]interface{}, changes *[]Change) {
+	keys := make([]interface{}, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, exists := a[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	for _, k := range keys {
+		kp := joinPath(p, fmt.Sprint(k))
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Path: kp, Op: OpRemoved, Old: av})
+		case !inA:
+			*changes = append(*changes, Change{Path: kp, Op: OpAdded, New: bv})
+		default:
+			diffValues(kp, av, bv, changes)
+		}
+	}
+}
+
+// DiffDocs returns the changes from the i'th to the j'th document
+func (s *Storage) DiffDocs(i, j int) ([]Change, error) {
+	a, err := s.GetDataFromIndex(i)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	b, err := s.GetDataFromIndex(j)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return Diff(a, b), nil
+}
+
+// DiffFile returns the changes from the documents of the Storage to
+// the documents of the file p, which is decoded by its extension.
+// Documents are compared by index, so paths start with the index
+// of the document, e.g. "[0].spec.replicas"
+func (s *Storage) DiffFile(p string) ([]Change, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	defer f.Close()
+
+	docs, err := CodecFor(p).Decode(f)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return Diff(s.GetAllData(), docs), nil
+}
+
+// DryRun runs f on an in memory copy of the Storage and returns the
+// changes that f did, in the same form as DiffFile. The Storage and
+// its local file are not changed, so changes such as an UpsertGlobal
+// or a MergeDBs can be reviewed before they are applied. The copy has
+// no local file, so calling Write on it returns an error
+func (s *Storage) DryRun(f func(s *Storage) error) ([]Change, error) {
+	c := &Storage{
+		SQL:         NewSQLFactory(),
+		state:       newStateFactory(),
+		Path:        "",
+		mem:         true,
+		keepEmpty:   s.keepEmpty,
+		codec:       s.fileCodec(),
+		aliasPolicy: s.aliasPolicy,
+		resolver:    s.resolver,
+		schemas:     append([]schemaRule(nil), s.schemas...),
+		kube:        s.kube,
+		labels:      s.labels,
+	}
+	*c.nextID = *s.nextID
+
+	for i, j := range s.data {
+		c.pushDataWithID(deepCopy(j), s.ids[i])
+	}
+	for k, v := range s.lib {
+		c.lib[k] = v
+	}
+	for k, v := range s.origins {
+		c.origins[k] = v
+	}
+	for k, v := range s.layouts {
+		c.layouts[k] = v
+	}
+	c.ad = s.ad
+
+	if err := f(c); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return Diff(s.GetAllData(), c.GetAllData()), nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImportDir imports the documents of all files in dir whose name
+// matches pattern (e.g. "*.yaml"). Patterns with a / are matched against
+// the slash separated path relative to dir instead (e.g. "nested/*.yaml").
+// An empty pattern matches all files. If recursive is true,
+// subdirectories are also imported. Files are
+// imported in lexical order and each document records the file it was
+// imported from, relative to dir. See DocOrigin
+func (s *Storage) ImportDir(dir, pattern string, recursive bool) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return wrapErr(err)
+	}
+
+	prevData, prevIDs, prevLib := s.data, s.ids, s.lib
+	docs := len(s.GetAllData())
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if p != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+		if strings.Contains(pattern, "/") {
+			name = filepath.ToSlash(rel)
+		}
+		if ok, _ := filepath.Match(pattern, name); !ok {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := s.readBufferWith(f, CodecFor(p)); err != nil {
+			return wrapErr(err)
+		}
+		s.pushBuffer(rel)
+
+		return nil
+	})
+	if err != nil {
+		for _, j := range s.ids[docs:] {
+			delete(s.origins, j)
+		}
+		s.data, s.ids, s.lib = prevData, prevIDs, prevLib
+		return wrapErr(err)
+	}
+
+	return s.commitImport(docs, prevData, prevIDs, prevLib)
+}
+
+// ExportDir writes the documents under dir. Documents that were
+// imported with ImportDir are written to their origin file, relative
+// to dir, in the order they appear in the Storage. Documents without an
+// origin are written to their own file named after the document's
+// name, e.g. dir/deployment/web.yaml. Documents that have neither an
+// origin nor a name, or whose file would be outside of dir (e.g. a name
+// such as ../x), return an error and no file is written
+func (s *Storage) ExportDir(dir string) error {
+	files := make([]string, 0)
+	docs := make(map[string][]int)
+
+	for i := range s.GetAllData() {
+		f, exists := s.DocOrigin(i)
+		if !exists {
+			n, exists := s.DocName(i)
+			if !exists {
+				return wrapErr(noDocTarget, i)
+			}
+			f = n + ".yaml"
+		}
+
+		f, err := dirFile(dir, f)
+		if err != nil {
+			return wrapErr(err)
+		}
+		if _, exists := docs[f]; !exists {
+			files = append(files, f)
+		}
+		docs[f] = append(docs[f], i)
+	}
+
+	for _, f := range files {
+		var buf bytes.Buffer
+		if err := s.writeDocsWith(&buf, docs[f], CodecFor(f)); err != nil {
+			return wrapErr(err)
+		}
+
+		if err := makeDirs(filepath.Dir(f), 0700); err != nil {
+			return wrapErr(err)
+		}
+
+		if err := ioutil.WriteFile(f, buf.Bytes(), 0600); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	return nil
+}
+
+// dirFile joins dir and the slash separated path f. Paths that
+// resolve outside of dir return an error
+func dirFile(dir, f string) (string, error) {
+	p := filepath.Join(dir, filepath.FromSlash(f))
+
+	rel, err := filepath.Rel(filepath.Clean(dir), p)
+	if err != nil {
+		return "", wrapErr(err)
+	}
+	if filepath.IsAbs(f) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", wrapErr(outsideDir, f, dir)
+	}
+
+	return p, nil
+}
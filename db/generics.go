@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TypeError is returned by the typed getters when the value of
+// a path can not be converted to the requested type
+type TypeError struct {
+	Path  string
+	Type  string
+	Value interface{}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf(typeMismatch, e.Path, e.Value, e.Type)
+}
+
+// Get returns the value of a given path from the active document
+// converted to T. Numeric values are widened when needed (e.g. an int
+// can be returned as int64 or float64), while slices and maps are
+// converted element by element. Structs are decoded using their yaml tags.
+// A *TypeError is returned if the value can not be converted to T
+func Get[T any](s *Storage, k string) (T, error) {
+	var out T
+
+	obj, err := s.GetPath(k)
+	if err != nil {
+		return out, wrapErr(err)
+	}
+
+	v, err := convertValue(k, obj, reflect.TypeOf(&out).Elem())
+	if err != nil {
+		return out, wrapErr(err)
+	}
+	reflect.ValueOf(&out).Elem().Set(v)
+
+	return out, nil
+}
+
+// GetOr works like Get but returns d if the path does not exist
+// or its value can not be converted to T
+func GetOr[T any](s *Storage, k string, d T) T {
+	v, err := Get[T](s, k)
+	if err != nil {
+		return d
+	}
+	return v
+}
+
+func convertValue(k string, v interface{}, t reflect.Type) (reflect.Value, error) {
+	mismatch := &TypeError{Path: k, Type: t.String(), Value: v}
+
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, mismatch
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := reflect.New(t).Elem()
+			if n.OverflowInt(rv.Int()) {
+				return reflect.Value{}, mismatch
+			}
+			n.SetInt(rv.Int())
+			return n, nil
+		case reflect.Uint64:
+			n := reflect.New(t).Elem()
+			if rv.Uint() > math.MaxInt64 || n.OverflowInt(int64(rv.Uint())) {
+				return reflect.Value{}, mismatch
+			}
+			n.SetInt(int64(rv.Uint()))
+			return n, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.ValueOf(float64(rv.Int())).Convert(t), nil
+		case reflect.Uint64:
+			return reflect.ValueOf(float64(rv.Uint())).Convert(t), nil
+		case reflect.Float32, reflect.Float64:
+			n := reflect.New(t).Elem()
+			if n.OverflowFloat(rv.Float()) {
+				return reflect.Value{}, mismatch
+			}
+			n.SetFloat(rv.Float())
+			return n, nil
+		}
+	case reflect.Slice:
+		array, isArray := v.([]interface{})
+		if !isArray {
+			return reflect.Value{}, mismatch
+		}
+
+		out := reflect.MakeSlice(t, 0, len(array))
+		for i, j := range array {
+			e, err := convertValue(k+".["+strconv.Itoa(i)+"]", j, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out = reflect.Append(out, e)
+		}
+		return out, nil
+	case reflect.Map:
+		obj, isMap := v.(map[interface{}]interface{})
+		if !isMap {
+			return reflect.Value{}, mismatch
+		}
+
+		out := reflect.MakeMapWithSize(t, len(obj))
+		for kn, vn := range obj {
+			p := strings.TrimPrefix(fmt.Sprintf("%s.%v", k, kn), ".")
+			key, err := convertValue(p, kn, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			e, err := convertValue(p, vn, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(key, e)
+		}
+		return out, nil
+	case reflect.Struct:
+		if getObjectType(v) != mapObj {
+			return reflect.Value{}, mismatch
+		}
+
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return reflect.Value{}, wrapErr(err)
+		}
+		out := reflect.New(t)
+		if err := yaml.Unmarshal(data, out.Interface()); err != nil {
+			return reflect.Value{}, mismatch
+		}
+		return out.Elem(), nil
+	}
+
+	return reflect.Value{}, mismatch
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"io"
+)
+
+// ExportOpts configures ExportTo
+type ExportOpts struct {
+	// Filter selects the documents that will be exported.
+	// If nil, all documents are exported
+	Filter DocFilter
+	// Codec encodes the documents. If nil, the YAMLCodec is used
+	Codec Codec
+}
+
+// ImportFrom for importing documents from a reader. It works the
+// same way as ImportDocs. Documents are decoded as yaml unless a
+// Codec is given
+func (s *Storage) ImportFrom(r io.Reader, c ...Codec) error {
+	return wrapErr(s.importFrom(r, codecOrYAML(c)))
+}
+
+// MergeFrom for merging a document read from r into the Active
+// Document. It works the same way as MergeDBs. The document is
+// decoded as yaml unless a Codec is given
+func (s *Storage) MergeFrom(r io.Reader, c ...Codec) error {
+	b := s.backup(s.GetAD())
+
+	err := s.SQL.mergeFrom(r, codecOrYAML(c), s.upsertTarget())
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
+}
+
+// ExportTo writes the documents to w as a multi document yaml stream.
+// Documents can be restricted by passing ExportOpts with a Filter
+func (s *Storage) ExportTo(w io.Writer, opts ...ExportOpts) error {
+	docs := s.allDocs()
+
+	var c Codec = YAMLCodec{}
+	if len(opts) > 0 && opts[0].Codec != nil {
+		c = opts[0].Codec
+	}
+
+	if len(opts) > 0 && opts[0].Filter != nil {
+		indexes, err := opts[0].Filter(s)
+		if err != nil {
+			return wrapErr(err)
+		}
+		for _, i := range indexes {
+			if err := s.IndexInRange(i); err != nil {
+				return wrapErr(err)
+			}
+		}
+		docs = indexes
+	}
+
+	return wrapErr(s.writeDocsWith(w, docs, c))
+}
+
+// ExportDoc writes the document with the given index to w
+func (s *Storage) ExportDoc(i int, w io.Writer) error {
+	if err := s.IndexInRange(i); err != nil {
+		return wrapErr(err)
+	}
+
+	return wrapErr(s.writeDocsWith(w, []int{i}, YAMLCodec{}))
+}
+
+// Convert decodes all documents from in with inFmt and encodes them
+// to out with outFmt, e.g. Convert(in, CodecFor(".yaml"), out,
+// CodecFor(".json")). Null documents are skipped
+func Convert(in io.Reader, inFmt Codec, out io.Writer, outFmt Codec) error {
+	docs, err := inFmt.Decode(in)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	data := make([]interface{}, 0, len(docs))
+	for _, j := range docs {
+		if j != nil {
+			data = append(data, j)
+		}
+	}
+
+	return wrapErr(outFmt.Encode(out, data))
+}
+
+// codecOrYAML returns the first Codec of c or the YAMLCodec if c is empty
+func codecOrYAML(c []Codec) Codec {
+	if len(c) > 0 && c[0] != nil {
+		return c[0]
+	}
+	return YAMLCodec{}
+}
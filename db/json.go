@@ -0,0 +1,81 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// JSONCodec reads and writes json. Decode accepts both a single
+// (indented) document and newline delimited documents. Encode writes
+// a single document indented and several documents as newline
+// delimited json. If NDJSON is true, documents are always written
+// one per line
+type JSONCodec struct {
+	NDJSON bool
+}
+
+// Decode for decoding a stream of json documents from r
+func (JSONCodec) Decode(r io.Reader) ([]interface{}, error) {
+	docs := make([]interface{}, 0)
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		var data interface{}
+		err := dec.Decode(&data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		docs = append(docs, fromJSON(data))
+	}
+
+	return docs, nil
+}
+
+// Encode for encoding docs to w
+func (c JSONCodec) Encode(w io.Writer, docs []interface{}) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	if len(docs) == 1 && !c.NDJSON {
+		enc.SetIndent("", "  ")
+	}
+
+	for _, j := range docs {
+		if err := enc.Encode(stringKeys(j)); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	return wrapErr(buf.Flush())
+}
+
+// fromJSON converts a decoded json object to the types that the
+// yaml decoder produces. Maps get interface keys and numbers become
+// ints or floats
+func fromJSON(o interface{}) interface{} {
+	switch obj := o.(type) {
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(obj))
+		for k, v := range obj {
+			m[k] = fromJSON(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range obj {
+			obj[i] = fromJSON(v)
+		}
+		return obj
+	case json.Number:
+		if i, err := strconv.Atoi(obj.String()); err == nil {
+			return i
+		}
+		f, _ := obj.Float64()
+		return f
+	}
+	return o
+}
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// KubeValidator validates Kubernetes manifests against OpenAPI and
+// CRD schemas that are loaded from a local directory. The schema
+// of each document is selected by the document's apiVersion and kind
+type KubeValidator struct {
+	schemas map[string]*jsonschema.Schema
+	strict  bool
+	counter int
+}
+
+// NewKubeValidatorFactory loads all the schemas found under dir. The
+// directory can contain (in json or yaml):
+//   - Kubernetes OpenAPI v2 documents (e.g. swagger.json)
+//   - Kubernetes OpenAPI v3 documents (e.g. openapi/v3/apis/apps/v1)
+//   - CustomResourceDefinition manifests
+//   - Standalone schemas that have x-kubernetes-group-version-kind set
+//
+// If strict is true, fields that are not defined in a schema are reported
+// as violations, unless the schema preserves unknown fields, and so are
+// documents whose apiVersion/kind has no schema. Otherwise such documents
+// are not validated
+func NewKubeValidatorFactory(dir string, strict bool) (*KubeValidator, error) {
+	k := &KubeValidator{
+		schemas: make(map[string]*jsonschema.Schema),
+		strict:  strict,
+	}
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".json", ".yaml", ".yml":
+			return wrapErr(k.loadFile(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return k, nil
+}
+
+// Kinds returns the apiVersion/kind keys that have a schema
+func (k *KubeValidator) Kinds() []string {
+	kinds := make([]string, 0, len(k.schemas))
+	for i := range k.schemas {
+		kinds = append(kinds, i)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Validate validates a document against the schema of its apiVersion and
+// kind. Documents without apiVersion or kind are not validated, while
+// documents with an apiVersion/kind that has no schema are reported only
+// in strict mode
+func (k *KubeValidator) Validate(o interface{}) []Violation {
+	obj, isMap := o.(map[interface{}]interface{})
+	if !isMap {
+		return nil
+	}
+
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return nil
+	}
+
+	schema, exists := k.schemas[kubeKey(apiVersion, kind)]
+	if !exists {
+		if !k.strict {
+			return nil
+		}
+		return []Violation{{Message: fmt.Sprintf(kubeNoSchema, apiVersion, kind)}}
+	}
+
+	err := schema.Validate(stringKeys(o))
+	if err == nil {
+		return nil
+	}
+
+	var vErr *jsonschema.ValidationError
+	if !errors.As(err, &vErr) {
+		return []Violation{{Message: err.Error()}}
+	}
+
+	var violations []Violation
+	for _, j := range schemaLeafErrors(vErr) {
+		violations = append(
+			violations,
+			Violation{
+				Path:    pointerToPath(j.InstanceLocation, o),
+				Message: j.Message,
+			},
+		)
+	}
+	return violations
+}
+
+func (k *KubeValidator) loadFile(p string) error {
+	f, err := ioutil.ReadFile(p)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(f))
+	for {
+		var data interface{}
+		err := dec.Decode(&data)
+		if err != nil {
+			if err.Error() == "EOF" {
+				return nil
+			}
+			return wrapErr(err)
+		}
+
+		doc, isMap := stringKeys(data).(map[string]interface{})
+		if !isMap {
+			continue
+		}
+
+		if err := k.loadDoc(doc); err != nil {
+			return wrapErr(fmt.Errorf("%s: %v", p, err))
+		}
+	}
+}
+
+func (k *KubeValidator) loadDoc(doc map[string]interface{}) error {
+	if doc["kind"] == "CustomResourceDefinition" {
+		return k.loadCRD(doc)
+	}
+
+	var refs map[string]string
+	switch {
+	case doc["definitions"] != nil:
+		refs = kubeRefs(doc["definitions"], "#/definitions/")
+	case doc["components"] != nil:
+		components, _ := doc["components"].(map[string]interface{})
+		refs = kubeRefs(components["schemas"], "#/components/schemas/")
+	default:
+		refs = kubeRefs(map[string]interface{}{"": doc}, "#")
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	url, compiler, err := k.compiler(doc)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	for key, ref := range refs {
+		schema, err := compiler.Compile(url + ref)
+		if err != nil {
+			return wrapErr(err)
+		}
+		k.schemas[key] = schema
+	}
+
+	return nil
+}
+
+func (k *KubeValidator) loadCRD(doc map[string]interface{}) error {
+	spec, _ := doc["spec"].(map[string]interface{})
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]interface{})
+	kind, _ := names["kind"].(string)
+
+	schemas := make(map[string]interface{})
+	versions, _ := spec["versions"].([]interface{})
+	for _, j := range versions {
+		version, _ := j.(map[string]interface{})
+		name, _ := version["name"].(string)
+		schema, _ := version["schema"].(map[string]interface{})
+		if schema["openAPIV3Schema"] != nil {
+			schemas[name] = schema["openAPIV3Schema"]
+		}
+	}
+
+	// apiextensions.k8s.io/v1beta1 CRDs have a single schema for all versions
+	if validation, _ := spec["validation"].(map[string]interface{}); validation["openAPIV3Schema"] != nil {
+		if version, _ := spec["version"].(string); version != "" {
+			schemas[version] = validation["openAPIV3Schema"]
+		}
+		for _, j := range versions {
+			version, _ := j.(map[string]interface{})
+			if name, _ := version["name"].(string); name != "" && schemas[name] == nil {
+				schemas[name] = validation["openAPIV3Schema"]
+			}
+		}
+	}
+
+	for version, schema := range schemas {
+		url, compiler, err := k.compiler(schema)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		s, err := compiler.Compile(url)
+		if err != nil {
+			return wrapErr(err)
+		}
+		k.schemas[kubeKey(group+"/"+version, kind)] = s
+	}
+
+	return nil
+}
+
+// compiler adds the given document as a new resource to a new compiler
+// and returns the resource's url along with the compiler
+func (k *KubeValidator) compiler(doc interface{}) (string, *jsonschema.Compiler, error) {
+	data, err := json.Marshal(kubeSchema(doc, k.strict))
+	if err != nil {
+		return "", nil, wrapErr(err)
+	}
+
+	k.counter++
+	url := fmt.Sprintf("dby://kube/%d.json", k.counter)
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft4
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return "", nil, wrapErr(err)
+	}
+
+	return url, compiler, nil
+}
+
+// kubeRefs returns a map with keys apiVersion/kind and values the
+// references of the schemas that have x-kubernetes-group-version-kind set
+func kubeRefs(o interface{}, prefix string) map[string]string {
+	refs := make(map[string]string)
+
+	definitions, _ := o.(map[string]interface{})
+	for name, j := range definitions {
+		definition, _ := j.(map[string]interface{})
+		gvks, _ := definition["x-kubernetes-group-version-kind"].([]interface{})
+		for _, l := range gvks {
+			gvk, _ := l.(map[string]interface{})
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+
+			apiVersion := version
+			if group != "" {
+				apiVersion = group + "/" + version
+			}
+
+			ref := prefix
+			if name != "" {
+				ref += strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+			}
+			refs[kubeKey(apiVersion, kind)] = ref
+		}
+	}
+
+	return refs
+}
+
+func kubeKey(apiVersion, kind string) string {
+	return strings.ToLower(apiVersion + "/" + kind)
+}
+
+// kubeSchema converts the Kubernetes OpenAPI extensions that have no
+// JSON Schema equivalent. int-or-string fields accept both integers and
+// strings, nullable fields accept null and, if strict is set, objects
+// with properties do not accept unknown fields
+func kubeSchema(o interface{}, strict bool) interface{} {
+	switch obj := o.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			if k == "enum" || k == "default" || k == "example" {
+				m[k] = v
+				continue
+			}
+			m[k] = kubeSchema(v, strict)
+		}
+
+		if m["format"] == "int-or-string" || m["x-kubernetes-int-or-string"] == true {
+			delete(m, "type")
+			delete(m, "format")
+			m["anyOf"] = []interface{}{
+				map[string]interface{}{"type": "integer"},
+				map[string]interface{}{"type": "string"},
+			}
+		}
+
+		if t, isString := m["type"].(string); isString && m["nullable"] == true {
+			m["type"] = []interface{}{t, "null"}
+		}
+
+		_, hasProperties := m["properties"].(map[string]interface{})
+		if strict && hasProperties && m["additionalProperties"] == nil &&
+			m["x-kubernetes-preserve-unknown-fields"] != true {
+			m["additionalProperties"] = false
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for i, v := range obj {
+			a[i] = kubeSchema(v, strict)
+		}
+		return a
+	}
+	return o
+}
+
+// SetKubeValidator sets a validator for Kubernetes manifests. Once set,
+// changes that would make a manifest invalid are rejected with a
+// *ValidationError. Passing nil removes the validator
+func (s *Storage) SetKubeValidator(k *KubeValidator) {
+	s.kube = k
+}
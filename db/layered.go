@@ -0,0 +1,155 @@
+package db
+
+import (
+	"strings"
+)
+
+// LayeredStorage reads through a base Storage and a list of overlays.
+// Each layer contributes its Active Document. Reads deep-merge the
+// layers in order, so maps are merged key by key and any other value
+// of a later layer replaces the value of the earlier ones. Writes go
+// to a single layer, the last overlay by default, so the base is
+// never changed by an override
+type LayeredStorage struct {
+	SQL    *SQL
+	layers []*Storage
+	target int
+}
+
+// NewLayeredStorage for creating a LayeredStorage from a base Storage
+// and its overlays, ordered from the lowest to the highest priority
+func NewLayeredStorage(base *Storage, overlays ...*Storage) *LayeredStorage {
+	layers := append([]*Storage{base}, overlays...)
+	return &LayeredStorage{
+		SQL:    NewSQLFactory(),
+		layers: layers,
+		target: len(layers) - 1,
+	}
+}
+
+// Layer returns the i'th layer. The base is layer 0
+func (l *LayeredStorage) Layer(i int) (*Storage, error) {
+	if i < 0 || i >= len(l.layers) {
+		return nil, wrapErr(noLayer, i)
+	}
+	return l.layers[i], nil
+}
+
+// SetWriteLayer for configuring the layer that Upsert and Delete change
+func (l *LayeredStorage) SetWriteLayer(i int) error {
+	if i < 0 || i >= len(l.layers) {
+		return wrapErr(noLayer, i)
+	}
+	l.target = i
+	return nil
+}
+
+// GetData returns the merged document of all layers
+func (l *LayeredStorage) GetData() interface{} {
+	var data interface{}
+	for _, j := range l.layers {
+		if d := j.GetData(); d != nil {
+			data = deepMerge(data, deepCopy(d))
+		}
+	}
+	return data
+}
+
+// GetPath returns the value of a path from the merged document
+func (l *LayeredStorage) GetPath(k string) (interface{}, error) {
+	dat := l.GetData()
+	obj, err := l.SQL.getPath(strings.Split(k, "."), &dat)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return *obj, nil
+}
+
+// Upsert adds or updates a path in the write layer
+func (l *LayeredStorage) Upsert(k string, i interface{}) error {
+	return wrapErr(l.layers[l.target].Upsert(k, i))
+}
+
+// Delete deletes a path from the write layer. If other layers
+// have the path, reads still return their value
+func (l *LayeredStorage) Delete(k string) error {
+	return wrapErr(l.layers[l.target].Delete(k))
+}
+
+// Explain returns the layers that supplied the value of a path. Maps
+// can be supplied by several layers, which are returned from the lowest
+// to the highest priority. Any other value has a single layer
+func (l *LayeredStorage) Explain(k string) ([]int, error) {
+	if _, err := l.GetPath(k); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	keys := strings.Split(k, ".")
+	found := make([]int, 0)
+	for i := range l.layers {
+		if l.supplies(i, keys) {
+			found = append(found, i)
+		}
+	}
+
+	return found, nil
+}
+
+// supplies reports if the value of the i'th layer at path k reaches
+// the merged document. This is the case if the layer has the path and
+// every later layer either lacks it or has maps where both layers
+// have the path or one of its parents
+func (l *LayeredStorage) supplies(i int, k []string) bool {
+	if _, exists := l.layerPath(i, k); !exists {
+		return false
+	}
+
+	for j := i + 1; j < len(l.layers); j++ {
+		for n := 1; n <= len(k); n++ {
+			v, exists := l.layerPath(j, k[:n])
+			if !exists {
+				break
+			}
+			w, _ := l.layerPath(i, k[:n])
+			if !isMap(v) || !isMap(w) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// layerPath returns the value of path k of the i'th layer
+func (l *LayeredStorage) layerPath(i int, k []string) (interface{}, bool) {
+	dat := l.layers[i].GetData()
+	obj, err := l.SQL.getPath(k, &dat)
+	if err != nil {
+		return nil, false
+	}
+	return *obj, true
+}
+
+// deepMerge merges b into a. Maps are merged key by key, any other
+// value of b replaces a
+func deepMerge(a, b interface{}) interface{} {
+	am, aIsMap := a.(map[interface{}]interface{})
+	bm, bIsMap := b.(map[interface{}]interface{})
+	if !aIsMap || !bIsMap {
+		return b
+	}
+
+	for k, v := range bm {
+		if old, exists := am[k]; exists {
+			am[k] = deepMerge(old, v)
+			continue
+		}
+		am[k] = v
+	}
+	return am
+}
+
+func isMap(o interface{}) bool {
+	return getObjectType(o) == mapObj
+}
@@ -0,0 +1,291 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// absentValue marks a path that does not exist on one side of a merge
+type absentValue struct{}
+
+var absent = absentValue{}
+
+// Conflict is a path that ours and theirs changed in different ways
+// since the base. A side that removed the path has a nil value and
+// its Deleted field set
+type Conflict struct {
+	Path          string
+	Base          interface{}
+	Ours          interface{}
+	Theirs        interface{}
+	OursDeleted   bool
+	TheirsDeleted bool
+}
+
+// MergeStrategy resolves a Conflict. It returns the merged value and
+// false if the path should not exist in the merged result
+type MergeStrategy func(c Conflict) (interface{}, bool, error)
+
+// MergeOurs is a MergeStrategy that keeps our side of all conflicts
+func MergeOurs(c Conflict) (interface{}, bool, error) {
+	return c.Ours, !c.OursDeleted, nil
+}
+
+// MergeTheirs is a MergeStrategy that keeps their side of all conflicts
+func MergeTheirs(c Conflict) (interface{}, bool, error) {
+	return c.Theirs, !c.TheirsDeleted, nil
+}
+
+// Merge3 merges the changes that ours and theirs did since their common
+// base. Maps are merged key by key and arrays index by index, so changes
+// on different paths never conflict. All conflicts are returned. They are
+// resolved by the MergeStrategy if one is given and keep ours otherwise
+func Merge3(base, ours, theirs interface{}, st ...MergeStrategy) (interface{}, []Conflict, error) {
+	m := &merger{conflicts: make([]Conflict, 0)}
+	if len(st) > 0 {
+		m.strategy = st[0]
+	}
+
+	for _, j := range []*interface{}{&base, &ours, &theirs} {
+		if n, err := normalize(*j); err == nil {
+			*j = n
+		}
+	}
+
+	v, err := m.merge("", base, ours, theirs)
+	if err != nil {
+		return nil, nil, wrapErr(err)
+	}
+	if v == absent {
+		v = nil
+	}
+
+	return v, m.conflicts, nil
+}
+
+// Merge3 merges the documents of theirs into the Storage (ours) given
+// their common base. Documents are merged by index, so the paths of
+// conflicts start with the index of the document, e.g. "[0].spec.replicas".
+// See Merge3 for how conflicts are resolved
+func (s *Storage) Merge3(base, theirs *Storage, st ...MergeStrategy) ([]Conflict, error) {
+	return s.merge3(base.GetAllData(), theirs.GetAllData(), st)
+}
+
+// Merge3Files works like Merge3 but reads the base and theirs from
+// files, which are decoded by their extension
+func (s *Storage) Merge3Files(base, theirs string, st ...MergeStrategy) ([]Conflict, error) {
+	docs := make([][]interface{}, 0, 2)
+	for _, p := range []string{base, theirs} {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+
+		d, err := CodecFor(p).Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		docs = append(docs, d)
+	}
+
+	return s.merge3(docs[0], docs[1], st)
+}
+
+func (s *Storage) merge3(base, theirs []interface{}, st []MergeStrategy) ([]Conflict, error) {
+	ours := s.GetAllData()
+	merged, conflicts, err := Merge3(base, ours, theirs, st...)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	docs, _ := merged.([]interface{})
+	data, ids, ad := s.data, s.ids, s.ad
+	lib, origins, layouts := make(map[string]int), make(map[int]string), make(map[int]layout)
+	for k, v := range s.lib {
+		lib[k] = v
+	}
+	for k, v := range s.origins {
+		origins[k] = v
+	}
+	for k, v := range s.layouts {
+		layouts[k] = v
+	}
+
+	s.ReloadData(docs)
+	if err := s.IndexInRange(s.GetAD()); err != nil {
+		s.ad = 0
+	}
+
+	var violations []Violation
+	for i := range s.GetAllData() {
+		if i < len(ours) {
+			violations = append(violations, s.newViolations(i, ours[i])...)
+			continue
+		}
+		violations = append(violations, s.validateDoc(i)...)
+	}
+	if len(violations) > 0 {
+		s.data, s.ids, s.ad = data, ids, ad
+		restore(s.lib, lib)
+		restore(s.origins, origins)
+		restore(s.layouts, layouts)
+		if s.parent != nil {
+			s.sync()
+		}
+		return nil, wrapErr(&ValidationError{Violations: violations})
+	}
+
+	return conflicts, s.stateReload()
+}
+
+// restore sets the entries of m to the ones of b. The map is
+// updated in place since it may be shared with views
+func restore[K comparable, V any](m, b map[K]V) {
+	for k := range m {
+		delete(m, k)
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+}
+
+type merger struct {
+	strategy  MergeStrategy
+	conflicts []Conflict
+}
+
+// merge returns the merged value of path p. Paths that do not exist
+// on a side have the absent value
+func (m *merger) merge(p string, b, o, t interface{}) (interface{}, error) {
+	switch {
+	case reflect.DeepEqual(o, t):
+		return o, nil
+	case reflect.DeepEqual(b, o):
+		return t, nil
+	case reflect.DeepEqual(b, t):
+		return o, nil
+	}
+
+	om, oIsMap := o.(map[interface{}]interface{})
+	tm, tIsMap := t.(map[interface{}]interface{})
+	bm, bIsMap := b.(map[interface{}]interface{})
+	if oIsMap && tIsMap && (bIsMap || b == absent) {
+		return m.mergeMaps(p, bm, om, tm)
+	}
+
+	oa, oIsArray := o.([]interface{})
+	ta, tIsArray := t.([]interface{})
+	ba, bIsArray := b.([]interface{})
+	if oIsArray && tIsArray && (bIsArray || b == absent) {
+		return m.mergeArrays(p, ba, oa, ta)
+	}
+
+	return m.conflict(p, b, o, t)
+}
+
+func (m *merger) mergeMaps(p string, b, o, t map[interface{}]interface{}) (interface{}, error) {
+	merged := make(map[interface{}]interface{})
+	for _, k := range mergeKeys(b, o, t) {
+		v, err := m.merge(joinPath(p, fmt.Sprint(k)), side(b, k), side(o, k), side(t, k))
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		if v != absent {
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+func (m *merger) mergeArrays(p string, b, o, t []interface{}) (interface{}, error) {
+	n := len(b)
+	if len(o) > n {
+		n = len(o)
+	}
+	if len(t) > n {
+		n = len(t)
+	}
+
+	merged := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		ip := joinPath(p, "["+strconv.Itoa(i)+"]")
+		v, err := m.merge(ip, item(b, i), item(o, i), item(t, i))
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		if v != absent {
+			merged = append(merged, v)
+		}
+	}
+	return merged, nil
+}
+
+// conflict records a conflict and returns its resolved value
+func (m *merger) conflict(p string, b, o, t interface{}) (interface{}, error) {
+	c := Conflict{
+		Path:          p,
+		Base:          present(b),
+		Ours:          present(o),
+		Theirs:        present(t),
+		OursDeleted:   o == absent,
+		TheirsDeleted: t == absent,
+	}
+	m.conflicts = append(m.conflicts, c)
+
+	if m.strategy == nil {
+		return o, nil
+	}
+
+	v, keep, err := m.strategy(c)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if !keep {
+		return absent, nil
+	}
+	return v, nil
+}
+
+// mergeKeys returns the keys of the given maps sorted by name
+func mergeKeys(maps ...map[interface{}]interface{}) []interface{} {
+	seen := make(map[interface{}]bool)
+	keys := make([]interface{}, 0)
+	for _, j := range maps {
+		for k := range j {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+func side(m map[interface{}]interface{}, k interface{}) interface{} {
+	if v, exists := m[k]; exists {
+		return v
+	}
+	return absent
+}
+
+func item(a []interface{}, i int) interface{} {
+	if i < len(a) {
+		return a[i]
+	}
+	return absent
+}
+
+func present(v interface{}) interface{} {
+	if v == absent {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NameCollision sets what SetNamesTemplate does when a generated
+// name already points to a different document
+type NameCollision int
+
+const (
+	// CollisionError returns an error and no name is set
+	CollisionError NameCollision = iota
+	// CollisionSuffix appends -1, -2, ... to the name until it is unique
+	CollisionSuffix
+	// CollisionSkip leaves the document without the generated name
+	CollisionSkip
+	// CollisionOverwrite points the name to the last document that
+	// generates it
+	CollisionOverwrite
+)
+
+var namePlaceholder = regexp.MustCompile(`{{\s*\.([^{}\s]*)\s*}}`)
+
+// SetNamesTemplate sets names automatically to the documents using a template.
+// Each {{.path}} placeholder in the template is replaced with the
+// value of that path, for example
+//
+//	{{.kind}}/{{.metadata.namespace}}/{{.metadata.name}}
+//
+// Other template actions, such as pipelines, are not supported.
+// Documents that miss any of the paths do not get a name, while paths
+// that do not have a string value return an error. The optional p sets
+// what happens when a generated name already exists (default CollisionError).
+// Names are set only if all documents were named without an error
+func (s *Storage) SetNamesTemplate(t string, p ...NameCollision) error {
+	policy := CollisionError
+	if len(p) > 0 {
+		policy = p[0]
+	}
+
+	placeholders := namePlaceholder.FindAllStringSubmatch(t, -1)
+	if len(placeholders) == 0 || strings.Contains(namePlaceholder.ReplaceAllString(t, ""), "{{") {
+		return wrapErr(invalidTemplate, t)
+	}
+	for _, j := range placeholders {
+		if err := checkKeyPath(strings.Split(j[1], ".")); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	names := make(map[string]int)
+	for i, j := range s.GetAllData() {
+		name, ok, err := s.docTemplateName(t, j)
+		if err != nil {
+			return wrapErr(err)
+		}
+		if !ok {
+			continue
+		}
+
+		unique := name
+		for k := 1; ; k++ {
+			doc, exists := names[unique]
+			if !exists {
+				doc, exists = s.LibIndex(unique)
+			}
+			if !exists || doc == i {
+				break
+			}
+
+			switch policy {
+			case CollisionSuffix:
+				unique = fmt.Sprintf("%s-%d", name, k)
+				continue
+			case CollisionSkip:
+				unique = ""
+			case CollisionOverwrite:
+			default:
+				return wrapErr(docExists, unique)
+			}
+			break
+		}
+
+		if unique != "" {
+			names[unique] = i
+		}
+	}
+
+	for k, v := range names {
+		if err := s.addDoc(k, v); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	return nil
+}
+
+// docTemplateName returns the name that template t generates for the
+// document o. If the document does not have all the paths of the
+// template, false is returned
+func (s *Storage) docTemplateName(t string, o interface{}) (string, bool, error) {
+	var err error
+	found := true
+
+	name := namePlaceholder.ReplaceAllStringFunc(t, func(p string) string {
+		if err != nil || !found {
+			return ""
+		}
+
+		k := namePlaceholder.FindStringSubmatch(p)[1]
+		obj, e := s.SQL.getPath(strings.Split(k, "."), &o)
+		if e != nil {
+			found = false
+			return ""
+		}
+
+		v, ok := (*obj).(string)
+		if !ok {
+			err = wrapErr(fieldNotString, k, *obj)
+			return ""
+		}
+		return v
+	})
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return strings.ToLower(name), found, nil
+}
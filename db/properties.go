@@ -0,0 +1,345 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PropertiesCodec reads and writes java style properties files.
+// Keys are split on dots into nested maps, so a.b=c is read as the
+// path a.b, while array items use the index syntax (list.[0]=a).
+// All values are read as strings. A properties file holds a single
+// document
+type PropertiesCodec struct{}
+
+// Decode for decoding the properties document from r
+func (PropertiesCodec) Decode(r io.Reader) ([]interface{}, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	doc := emptyMap()
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		for continuesLine(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		k, v := splitProperty(line)
+		if err := checkKeyPath(strings.Split(k, ".")); err != nil {
+			return nil, wrapErr(invalidLine, i+1, err)
+		}
+		setPath(doc, strings.Split(k, "."), v)
+	}
+
+	return []interface{}{indexedMapsToArrays(doc)}, nil
+}
+
+// Encode for encoding the document of docs to w. Nested maps
+// and arrays are flattened into dotted keys
+func (PropertiesCodec) Encode(w io.Writer, docs []interface{}) error {
+	doc, err := singleDoc("properties", docs)
+	if err != nil {
+		return wrapErr(err)
+	}
+	switch doc.(type) {
+	case map[interface{}]interface{}, []interface{}:
+	default:
+		return wrapErr(notAMap)
+	}
+
+	flat := make(map[string]string)
+	flatten("", doc, flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := bufio.NewWriter(w)
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s=%s\n", escapeProperty(k, true), escapeProperty(flat[k], false))
+	}
+
+	return wrapErr(buf.Flush())
+}
+
+// EnvCodec reads and writes dotenv (.env) files. Lines have the form
+// KEY=value, optionally prefixed by export. Values can be single or
+// double quoted. All values are read as strings and the document must
+// be a flat map of scalars on Encode. A .env file holds a single
+// document
+type EnvCodec struct{}
+
+// Decode for decoding the env document from r
+func (EnvCodec) Decode(r io.Reader) ([]interface{}, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	doc := emptyMap()
+	for i, j := range lines {
+		line := strings.TrimSpace(j)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		e := strings.Index(line, "=")
+		if e < 1 {
+			return nil, wrapErr(invalidLine, i+1, line)
+		}
+
+		v, err := unquoteEnv(strings.TrimSpace(line[e+1:]))
+		if err != nil {
+			return nil, wrapErr(invalidLine, i+1, err)
+		}
+		doc[strings.TrimSpace(line[:e])] = v
+	}
+
+	return []interface{}{doc}, nil
+}
+
+// Encode for encoding the document of docs to w. Keys are
+// written in lexical order
+func (EnvCodec) Encode(w io.Writer, docs []interface{}) error {
+	doc, err := singleDoc("env", docs)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	obj, isMap := stringKeys(doc).(map[string]interface{})
+	if !isMap {
+		return wrapErr(notAMap)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k, v := range obj {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return wrapErr(notAScalar, k, v)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := bufio.NewWriter(w)
+	for _, k := range keys {
+		v := ""
+		if obj[k] != nil {
+			v = fmt.Sprint(obj[k])
+		}
+		if strings.ContainsAny(v, " \t\n\"'#\\$") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(buf, "%s=%s\n", k, v)
+	}
+
+	return wrapErr(buf.Flush())
+}
+
+// readLines returns all lines of r
+func readLines(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+
+	return lines, wrapErr(scanner.Err())
+}
+
+// continuesLine reports if a properties line ends with an odd
+// number of backslashes, which means it continues on the next line
+func continuesLine(l string) bool {
+	n := 0
+	for i := len(l) - 1; i >= 0 && l[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a properties line on the first unescaped
+// separator (=, : or whitespace) and unescapes the key and the value
+func splitProperty(l string) (string, string) {
+	var k, v strings.Builder
+	i := 0
+	for ; i < len(l); i++ {
+		c := l[i]
+		if c == '\\' && i+1 < len(l) {
+			i++
+			k.WriteString(unescapeChar(l[i]))
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		k.WriteByte(c)
+	}
+
+	rest := strings.TrimLeft(l[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == '\\' && i+1 < len(rest) {
+			i++
+			v.WriteString(unescapeChar(rest[i]))
+			continue
+		}
+		v.WriteByte(rest[i])
+	}
+
+	return k.String(), v.String()
+}
+
+func unescapeChar(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'f':
+		return "\f"
+	}
+	return string(c)
+}
+
+// escapeProperty escapes the special characters of a properties
+// key or value
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if key {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(c)
+		case ' ':
+			if key || i == 0 {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// unquoteEnv removes the quotes of an env value. Double quoted values
+// are unescaped, while unquoted values end at an inline comment
+func unquoteEnv(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		e := strings.LastIndex(v, `"`)
+		if e < 1 {
+			return "", wrapErr(unterminatedQuote, v)
+		}
+		return strconv.Unquote(v[:e+1])
+	case strings.HasPrefix(v, "'"):
+		e := strings.LastIndex(v, "'")
+		if e < 1 {
+			return "", wrapErr(unterminatedQuote, v)
+		}
+		return v[1:e], nil
+	}
+
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	return v, nil
+}
+
+// setPath sets v under the path k of m. Missing or non map
+// objects in the path are replaced by maps
+func setPath(m map[interface{}]interface{}, k []string, v interface{}) {
+	for _, j := range k[:len(k)-1] {
+		next, isMap := m[j].(map[interface{}]interface{})
+		if !isMap {
+			next = emptyMap()
+			m[j] = next
+		}
+		m = next
+	}
+	m[k[len(k)-1]] = v
+}
+
+// indexedMapsToArrays converts maps whose keys are the indexes
+// [0] to [n-1] into arrays
+func indexedMapsToArrays(o interface{}) interface{} {
+	obj, isMap := o.(map[interface{}]interface{})
+	if !isMap {
+		return o
+	}
+
+	for k, v := range obj {
+		obj[k] = indexedMapsToArrays(v)
+	}
+
+	if len(obj) == 0 {
+		return obj
+	}
+
+	a := make([]interface{}, len(obj))
+	for i := range a {
+		v, exists := obj["["+strconv.Itoa(i)+"]"]
+		if !exists {
+			return obj
+		}
+		a[i] = v
+	}
+	return a
+}
+
+// flatten adds all scalar values of o to out under their dotted path
+func flatten(p string, o interface{}, out map[string]string) {
+	join := func(k string) string {
+		if p == "" {
+			return k
+		}
+		return p + "." + k
+	}
+
+	switch obj := o.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range obj {
+			flatten(join(fmt.Sprint(k)), v, out)
+		}
+	case []interface{}:
+		for i, v := range obj {
+			flatten(join("["+strconv.Itoa(i)+"]"), v, out)
+		}
+	case nil:
+		out[p] = ""
+	default:
+		out[p] = fmt.Sprint(obj)
+	}
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RenderData is the data that Render executes templates with
+type RenderData struct {
+	// Docs holds all documents
+	Docs []interface{}
+	// Names maps document names to their index in Docs
+	Names map[string]int
+	// Doc is the Active Document and AD its index
+	Doc interface{}
+	AD  int
+}
+
+// Render executes the Go template t with the documents of the Storage
+// (see RenderData) and writes the result to w. Templates can use
+//   - getPath "some.path" ["doc name" or index]: the value of a path in the
+//     Active Document or in the given document. References are expanded
+//     if a Resolver is set
+//   - toYaml: a value as yaml
+//   - indent n: indents all lines of a string by n spaces
+//
+// Nothing is written if the template fails
+func (s *Storage) Render(t string, w io.Writer) error {
+	return wrapErr(s.render("dby", t, w))
+}
+
+// RenderFile works like Render but reads the template from the file p
+func (s *Storage) RenderFile(p string, w io.Writer) error {
+	t, err := ioutil.ReadFile(p)
+	if err != nil {
+		return wrapErr(err)
+	}
+	return wrapErr(s.render(filepath.Base(p), string(t), w))
+}
+
+func (s *Storage) render(name, t string, w io.Writer) error {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"getPath": s.renderPath,
+		"toYaml":  toYaml,
+		"indent":  indent,
+	}).Parse(t)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	data := RenderData{
+		Docs:  s.GetAllData(),
+		Names: s.Lib(),
+		Doc:   s.GetData(),
+		AD:    s.GetAD(),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return wrapErr(err)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return wrapErr(err)
+}
+
+// renderPath returns the value of path k of the Active Document or,
+// if doc is given, of the document with that name or index
+func (s *Storage) renderPath(k string, doc ...interface{}) (interface{}, error) {
+	i := s.GetAD()
+	if len(doc) > 0 {
+		switch d := doc[0].(type) {
+		case int:
+			if err := s.IndexInRange(d); err != nil {
+				return nil, wrapErr(err)
+			}
+			i = d
+		case string:
+			j, exists := s.LibIndex(d)
+			if !exists {
+				return nil, wrapErr(docNotExists, strings.ToLower(d))
+			}
+			i = j
+		default:
+			return nil, wrapErr(notAType, "doc name or index")
+		}
+	}
+
+	c := s.GetAD()
+	s.SetAD(i)
+	defer s.SetAD(c)
+
+	return s.GetPath(k)
+}
+
+// toYaml returns v as yaml without the trailing newline
+func toYaml(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", wrapErr(err)
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// indent adds n spaces before each line of s
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
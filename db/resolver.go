@@ -0,0 +1,174 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Resolver expands references in string values that are returned by
+// GetPath, GetFirst and their Global variants. References are
+//   - ${NAME}: the environment variable NAME
+//   - ${NAME:-default}: NAME or default if NAME is unset or empty
+//   - ${ref:some.path}: the value of a path in the same document
+//   - ${doc:name:some.path}: the value of a path in a named document
+//
+// A value that is a single path reference keeps the type of the
+// referenced value, e.g. an int. $${ is written as a literal ${
+type Resolver struct {
+	// LookupEnv returns the value of an environment variable.
+	// Defaults to os.LookupEnv
+	LookupEnv func(string) (string, bool)
+}
+
+// SetResolver for configuring a Resolver that expands references on
+// read. A nil Resolver disables expansion. GetPathRaw and GetFirstRaw
+// always return the values as they are stored
+func (s *Storage) SetResolver(r *Resolver) *Storage {
+	s.resolver = r
+	return s
+}
+
+// resolve returns v with the references of the i'th document expanded.
+// Maps and arrays are copied, so the stored values are not changed
+func (s *Storage) resolve(i int, v interface{}) (interface{}, error) {
+	if s.resolver == nil {
+		return v, nil
+	}
+	return s.resolver.expand(s, i, v, nil)
+}
+
+func (r *Resolver) expand(s *Storage, i int, v interface{}, seen []string) (interface{}, error) {
+	switch obj := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(obj))
+		for k, j := range obj {
+			e, err := r.expand(s, i, j, seen)
+			if err != nil {
+				return nil, wrapErr(err)
+			}
+			m[k] = e
+		}
+		return m, nil
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for n, j := range obj {
+			e, err := r.expand(s, i, j, seen)
+			if err != nil {
+				return nil, wrapErr(err)
+			}
+			a[n] = e
+		}
+		return a, nil
+	case string:
+		return r.expandString(s, i, obj, seen)
+	}
+	return v, nil
+}
+
+// expandString expands the references of str. A str that is a single
+// path reference is replaced by the referenced value
+func (r *Resolver) expandString(s *Storage, i int, str string, seen []string) (interface{}, error) {
+	if !strings.Contains(str, "${") {
+		return str, nil
+	}
+
+	if strings.HasPrefix(str, "${") && strings.Index(str, "}") == len(str)-1 {
+		expr := str[2 : len(str)-1]
+		if strings.HasPrefix(expr, "ref:") || strings.HasPrefix(expr, "doc:") {
+			return r.lookup(s, i, expr, seen)
+		}
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(str, "${")
+		if start < 0 {
+			break
+		}
+		if start > 0 && str[start-1] == '$' {
+			b.WriteString(str[:start-1] + "${")
+			str = str[start+2:]
+			continue
+		}
+
+		end := strings.Index(str[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		v, err := r.lookup(s, i, str[start+2:end], seen)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		switch v.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return nil, wrapErr(notAScalar, str[start:end+1], v)
+		}
+
+		b.WriteString(str[:start] + fmt.Sprint(v))
+		str = str[end+1:]
+	}
+	b.WriteString(str)
+
+	return b.String(), nil
+}
+
+// lookup returns the value of the reference expr (without ${ and })
+func (r *Resolver) lookup(s *Storage, i int, expr string, seen []string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(expr, "ref:"):
+		return r.lookupPath(s, i, strings.TrimPrefix(expr, "ref:"), seen)
+	case strings.HasPrefix(expr, "doc:"):
+		ref := strings.SplitN(strings.TrimPrefix(expr, "doc:"), ":", 2)
+		if len(ref) != 2 {
+			return nil, wrapErr(invalidReference, expr)
+		}
+		j, exists := s.LibIndex(ref[0])
+		if !exists {
+			return nil, wrapErr(docNotExists, ref[0])
+		}
+		return r.lookupPath(s, j, ref[1], seen)
+	}
+
+	name, def, hasDefault := strings.Cut(expr, ":-")
+	if !envName.MatchString(name) {
+		return nil, wrapErr(invalidReference, expr)
+	}
+
+	lookupEnv := r.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	v, exists := lookupEnv(name)
+	if hasDefault && v == "" {
+		return def, nil
+	}
+	if !exists {
+		return nil, wrapErr(envNotSet, name)
+	}
+	return v, nil
+}
+
+// lookupPath returns the expanded value of path k of the i'th document
+func (r *Resolver) lookupPath(s *Storage, i int, k string, seen []string) (interface{}, error) {
+	ref := fmt.Sprintf("%d:%s", s.ids[i], k)
+	for _, j := range seen {
+		if j == ref {
+			return nil, wrapErr(referenceCycle, k)
+		}
+	}
+
+	dat := s.data[i]
+	obj, err := s.SQL.getPath(strings.Split(k, "."), &dat)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return r.expand(s, i, *obj, append(seen[:len(seen):len(seen)], ref))
+}
@@ -0,0 +1,312 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// AllDocs can be given as a target to SetSchema for
+// validating all documents against a schema
+const AllDocs = "*"
+
+// Violation describes a single schema violation. Path is the
+// dot path of the invalid value in the document (e.g. spec.ports.[0].port)
+type Violation struct {
+	Doc     int
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("doc [%d] path [%s]: %s", v.Doc, v.Path, v.Message)
+}
+
+// ValidationError is returned when a change would make one or more
+// documents invalid. The change is reverted before the error is returned
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msg := make([]string, 0, len(e.Violations))
+	for _, j := range e.Violations {
+		msg = append(msg, j.String())
+	}
+	return fmt.Sprintf(schemaViolation, strings.Join(msg, "; "))
+}
+
+type schemaRule struct {
+	target   string
+	selector []requirement
+	schema   *jsonschema.Schema
+}
+
+// SetSchema sets a JSON Schema for the documents that match the target.
+// The target is either AllDocs, a document name or a label selector
+// (e.g. "app=web,tier!=db"). Targets that name a document match only
+// that document, while other targets match the documents whose labels
+// match the selector. The schema can be given either as JSON or as YAML.
+// Setting a schema for a target that already has one replaces the old schema
+//
+// Once a schema is set, changes that would make a matching document
+// invalid are rejected with a *ValidationError
+func (s *Storage) SetSchema(target string, b []byte) error {
+	var raw interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return wrapErr(err)
+	}
+
+	data, err := json.Marshal(stringKeys(raw))
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	url := fmt.Sprintf("dby://schemas/%d.json", len(s.schemas))
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return wrapErr(err)
+	}
+
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	// Targets that are not valid selectors can only match a name
+	selector, err := parseSelector(target)
+	if err != nil || strings.TrimSpace(target) == "" {
+		selector = nil
+	}
+
+	rule := schemaRule{
+		target:   strings.ToLower(target),
+		selector: selector,
+		schema:   schema,
+	}
+	for i, j := range s.schemas {
+		if j.target == rule.target {
+			s.schemas[i] = rule
+			return nil
+		}
+	}
+	s.schemas = append(s.schemas, rule)
+
+	return nil
+}
+
+// UnsetSchema removes the schema of the given target
+func (s *Storage) UnsetSchema(target string) {
+	target = strings.ToLower(target)
+	for i, j := range s.schemas {
+		if j.target == target {
+			s.schemas = append(s.schemas[:i], s.schemas[i+1:]...)
+			return
+		}
+	}
+}
+
+// Validate validates all documents against their schemas and
+// returns every violation that was found
+func (s *Storage) Validate() []Violation {
+	var violations []Violation
+	for i := range s.GetAllData() {
+		violations = append(violations, s.validateDoc(i)...)
+	}
+	return violations
+}
+
+func (s *Storage) validateDoc(i int) []Violation {
+	data, err := s.GetDataFromIndex(i)
+	if err != nil {
+		return nil
+	}
+	return s.validateData(i, data)
+}
+
+// validateData validates data as the i'th document
+func (s *Storage) validateData(i int, data interface{}) []Violation {
+	var violations []Violation
+
+	for _, j := range s.schemas {
+		if !s.schemaMatches(j, i, data) {
+			continue
+		}
+
+		err := j.schema.Validate(stringKeys(data))
+		if err == nil {
+			continue
+		}
+
+		var vErr *jsonschema.ValidationError
+		if !errors.As(err, &vErr) {
+			violations = append(violations, Violation{Doc: i, Message: err.Error()})
+			continue
+		}
+
+		for _, l := range schemaLeafErrors(vErr) {
+			violations = append(
+				violations,
+				Violation{
+					Doc:     i,
+					Path:    pointerToPath(l.InstanceLocation, data),
+					Message: l.Message,
+				},
+			)
+		}
+	}
+
+	if s.kube != nil {
+		for _, j := range s.kube.Validate(data) {
+			j.Doc = i
+			violations = append(violations, j)
+		}
+	}
+
+	sort.SliceStable(violations, func(a, b int) bool {
+		return violations[a].Path < violations[b].Path
+	})
+
+	return violations
+}
+
+// schemaMatches reports if the document with index i and data d is
+// a target of the schema rule r
+func (s *Storage) schemaMatches(r schemaRule, i int, d interface{}) bool {
+	if r.target == AllDocs {
+		return true
+	}
+	if k, exists := s.LibIndex(r.target); exists {
+		return k == i
+	}
+	if r.selector == nil {
+		return false
+	}
+	return s.docMatches(r.selector, d)
+}
+
+// backup returns a copy of the given documents if any schema has
+// been set. The copy is used by commit to revert invalid changes
+func (s *Storage) backup(docs ...int) map[int]interface{} {
+	if len(s.schemas) == 0 && s.kube == nil {
+		return nil
+	}
+
+	b := make(map[int]interface{}, len(docs))
+	for _, i := range docs {
+		data, err := s.GetDataFromIndex(i)
+		if err != nil {
+			continue
+		}
+		b[i] = deepCopy(data)
+	}
+	return b
+}
+
+// allDocs returns the indexes of all documents
+func (s *Storage) allDocs() []int {
+	docs := make([]int, 0, len(s.GetAllData()))
+	for i := range s.GetAllData() {
+		docs = append(docs, i)
+	}
+	return docs
+}
+
+// commit validates the documents in b. If the change added a violation
+// to any of them, all documents are restored from b and a *ValidationError
+// is returned. Otherwise the state is reloaded. Violations that the
+// documents already had do not reject the change
+func (s *Storage) commit(b map[int]interface{}) error {
+	var violations []Violation
+
+	docs := make([]int, 0, len(b))
+	for i := range b {
+		docs = append(docs, i)
+	}
+	sort.Ints(docs)
+
+	for _, i := range docs {
+		violations = append(violations, s.newViolations(i, b[i])...)
+	}
+
+	if len(violations) > 0 {
+		for i, j := range b {
+			s.SetDataFromIndex(j, i)
+		}
+		if s.parent != nil {
+			s.sync()
+		}
+		return wrapErr(&ValidationError{Violations: violations})
+	}
+
+	return s.stateReload()
+}
+
+// newViolations returns the violations of the i'th document that
+// its previous data, old, did not have
+func (s *Storage) newViolations(i int, old interface{}) []Violation {
+	prev := make(map[Violation]bool)
+	for _, j := range s.validateData(i, old) {
+		prev[j] = true
+	}
+
+	var violations []Violation
+	for _, j := range s.validateDoc(i) {
+		if !prev[j] {
+			violations = append(violations, j)
+		}
+	}
+	return violations
+}
+
+// schemaLeafErrors returns the errors that have no causes
+func schemaLeafErrors(e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(e.Causes) == 0 {
+		return []*jsonschema.ValidationError{e}
+	}
+
+	var leafs []*jsonschema.ValidationError
+	for _, j := range e.Causes {
+		leafs = append(leafs, schemaLeafErrors(j)...)
+	}
+	return leafs
+}
+
+// pointerToPath converts a JSON pointer (e.g. /spec/ports/0) to a dot
+// path (e.g. spec.ports.[0]). The object is used to find which parts
+// of the pointer are array indexes
+func pointerToPath(p string, o interface{}) string {
+	var keys []string
+
+	for _, j := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+		if j == "" {
+			continue
+		}
+		j = strings.ReplaceAll(strings.ReplaceAll(j, "~1", "/"), "~0", "~")
+
+		switch obj := o.(type) {
+		case []interface{}:
+			keys = append(keys, "["+j+"]")
+			if i, err := strconv.Atoi(j); err == nil && i < len(obj) {
+				o = obj[i]
+				continue
+			}
+		case map[interface{}]interface{}:
+			keys = append(keys, j)
+			o = obj[j]
+			continue
+		default:
+			keys = append(keys, j)
+		}
+		o = nil
+	}
+
+	return strings.Join(keys, ".")
+}
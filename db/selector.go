@@ -0,0 +1,185 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultLabelsPath is the path that selectors read labels from,
+// unless a different path is set with SetLabelsPath
+const DefaultLabelsPath = "metadata.labels"
+
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// requirement is a single term of a label selector, e.g. env in (prod,staging)
+type requirement struct {
+	key    string
+	op     string
+	values []string
+}
+
+// parseSelector parses a Kubernetes style label selector. Supported
+// requirements are key=value, key==value, key!=value, key in (v1,v2),
+// key notin (v1,v2), key and !key. Requirements are separated by commas
+func parseSelector(sel string) ([]requirement, error) {
+	var terms []string
+	var depth, start int
+
+	for i, j := range sel {
+		switch j {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, sel[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, wrapErr(invalidSelector, sel)
+		}
+	}
+	if depth != 0 {
+		return nil, wrapErr(invalidSelector, sel)
+	}
+	terms = append(terms, sel[start:])
+
+	reqs := make([]requirement, 0, len(terms))
+	for _, j := range terms {
+		r, err := parseRequirement(strings.TrimSpace(j))
+		if err != nil {
+			return nil, wrapErr(invalidSelector, sel)
+		}
+		reqs = append(reqs, r)
+	}
+
+	return reqs, nil
+}
+
+func parseRequirement(t string) (requirement, error) {
+	var r requirement
+
+	switch {
+	case setRequirement.MatchString(t):
+		m := setRequirement.FindStringSubmatch(t)
+		r.key, r.op = m[1], m[2]
+		for _, j := range strings.Split(m[3], ",") {
+			r.values = append(r.values, strings.TrimSpace(j))
+		}
+	case strings.HasPrefix(t, "!"):
+		r.key, r.op = strings.TrimSpace(t[1:]), "!"
+	case strings.Contains(t, "!="):
+		kv := strings.SplitN(t, "!=", 2)
+		r.key, r.op, r.values = strings.TrimSpace(kv[0]), "!=", []string{strings.TrimSpace(kv[1])}
+	case strings.Contains(t, "=="):
+		kv := strings.SplitN(t, "==", 2)
+		r.key, r.op, r.values = strings.TrimSpace(kv[0]), "=", []string{strings.TrimSpace(kv[1])}
+	case strings.Contains(t, "="):
+		kv := strings.SplitN(t, "=", 2)
+		r.key, r.op, r.values = strings.TrimSpace(kv[0]), "=", []string{strings.TrimSpace(kv[1])}
+	default:
+		r.key, r.op = t, ""
+	}
+
+	if r.key == "" || strings.ContainsAny(r.key, " \t!=()") {
+		return r, fmt.Errorf(invalidSelector, t)
+	}
+	for _, j := range r.values {
+		if strings.ContainsAny(j, " \t!=()") {
+			return r, fmt.Errorf(invalidSelector, t)
+		}
+	}
+
+	return r, nil
+}
+
+// matches checks if the given labels satisfy the requirement
+func (r requirement) matches(labels map[interface{}]interface{}) bool {
+	v, exists := labels[r.key]
+	value := fmt.Sprint(v)
+
+	switch r.op {
+	case "":
+		return exists
+	case "!":
+		return !exists
+	case "=", "in":
+		return exists && containsString(r.values, value)
+	case "!=", "notin":
+		return !exists || !containsString(r.values, value)
+	}
+	return false
+}
+
+func containsString(l []string, v string) bool {
+	for _, j := range l {
+		if j == v {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLabelsPath sets the path that selectors read the labels of
+// each document from. Default is DefaultLabelsPath
+func (s *Storage) SetLabelsPath(p string) *Storage {
+	s.labels = p
+	return s
+}
+
+// Select returns the indexes of the documents that match a Kubernetes
+// style label selector, e.g. "app=web,tier!=db,env in (prod,staging)".
+// An empty selector matches all documents
+func (s *Storage) Select(sel string) ([]int, error) {
+	var reqs []requirement
+	if strings.TrimSpace(sel) != "" {
+		var err error
+		reqs, err = parseSelector(sel)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+	}
+
+	docs := make([]int, 0)
+	for i, j := range s.GetAllData() {
+		if s.docMatches(reqs, j) {
+			docs = append(docs, i)
+		}
+	}
+
+	return docs, nil
+}
+
+// docMatches reports if the labels of document d match all requirements
+func (s *Storage) docMatches(reqs []requirement, d interface{}) bool {
+	labelsPath := s.labels
+	if labelsPath == "" {
+		labelsPath = DefaultLabelsPath
+	}
+
+	labels := emptyMap()
+	if obj, err := s.SQL.getPath(strings.Split(labelsPath, "."), &d); err == nil {
+		if m, isMap := (*obj).(map[interface{}]interface{}); isMap {
+			labels = m
+		}
+	}
+
+	for _, r := range reqs {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectDocs returns the indexes of the documents that match the
+// selector. If no selector is given all documents are returned
+func (s *Storage) selectDocs(sel []string) ([]int, error) {
+	if len(sel) == 0 {
+		return s.allDocs(), nil
+	}
+	return s.Select(sel[0])
+}
@@ -1,7 +1,8 @@
 package db
 
 import (
-	"io/ioutil"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 
@@ -298,6 +299,10 @@ func (s *SQL) upsertRecursive(k []string, o, v interface{}) error {
 		return wrapErr(err)
 	}
 
+	if arr, isArray := o.([]interface{}); isArray {
+		return wrapErr(s.upsertIndex(k, arr, v))
+	}
+
 	obj, err := interfaceToMap(o)
 	if err != nil {
 		return wrapErr(err)
@@ -333,9 +338,37 @@ func (s *SQL) upsertRecursive(k []string, o, v interface{}) error {
 	return nil
 }
 
-func (s *SQL) mergeDBs(path string, o interface{}) error {
-	var dataNew interface{}
+// upsertIndex sets v to the array item that k[0] indexes. Missing
+// paths under the item are created the same way as upsertRecursive
+func (s *SQL) upsertIndex(k []string, o []interface{}, v interface{}) error {
+	i, err := s.getIndex(k[0])
+	if err != nil {
+		return wrapErr(err)
+	}
 
+	if i < 0 || i > len(o)-1 {
+		return wrapErr(
+			arrayOutOfRange,
+			strconv.Itoa(i),
+			strconv.Itoa(len(o)-1),
+		)
+	}
+
+	if len(k) == 1 {
+		o[i] = v
+		return nil
+	}
+
+	switch getObjectType(o[i]) {
+	case mapObj, arrayObj:
+	default:
+		o[i] = emptyMap()
+	}
+
+	return wrapErr(s.upsertRecursive(k[1:], o[i], v))
+}
+
+func (s *SQL) mergeDBs(path string, c Codec, o interface{}) error {
 	ok, err := fileExists(path)
 	if err != nil {
 		return wrapErr(err)
@@ -345,12 +378,26 @@ func (s *SQL) mergeDBs(path string, o interface{}) error {
 		return wrapErr(fileNotExist, path)
 	}
 
-	f, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return wrapErr(err)
 	}
+	defer f.Close()
+
+	return wrapErr(s.mergeFrom(f, c, o))
+}
 
-	yaml.Unmarshal(f, &dataNew)
+// mergeFrom merges the first document that c decodes from r into o
+func (s *SQL) mergeFrom(r io.Reader, c Codec, o interface{}) error {
+	var dataNew interface{}
+
+	docs, err := c.Decode(r)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if len(docs) > 0 {
+		dataNew = docs[0]
+	}
 
 	obj, err := interfaceToMap(dataNew)
 	if err != nil {
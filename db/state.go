@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -9,31 +10,50 @@ const (
 	ire = "index error"
 )
 
-// state struct used by dby storage
+// state struct used by dby storage. Each document in data has a stable
+// id in the same position of ids. The lib maps document names to ids, so
+// names keep pointing to the right document when documents are removed
+// or moved. nextID is shared between a Storage and its views, so ids
+// are unique across all of them. origins maps ids to the file that a
+// document was imported from and layouts maps ids to the layout (key
+// order, anchors and aliases) that a document had when it was read
 type state struct {
-	data   []interface{}
-	buffer []*interface{}
-	lib    map[string]int
-	ad     int
+	data         []interface{}
+	ids          []int
+	nextID       *int
+	buffer       []*interface{}
+	layoutBuffer []layout
+	lib          map[string]int
+	origins      map[int]string
+	layouts      map[int]layout
+	ad           int
 }
 
 // newStateFactory for creating a new v3 State
 func newStateFactory() *state {
 	s := state{
-		data:   make([]interface{}, 0),
-		buffer: make([]*interface{}, 0),
-		lib:    make(map[string]int),
+		data:    make([]interface{}, 0),
+		ids:     make([]int, 0),
+		nextID:  new(int),
+		buffer:  make([]*interface{}, 0),
+		lib:     make(map[string]int),
+		origins: make(map[int]string),
+		layouts: make(map[int]layout),
 	}
 	return &s
 }
 
 // Clear for clearing the v3 state
 func (c *state) Clear() {
-	c.data, c.buffer, c.lib = nil, nil, nil
+	c.data, c.ids, c.buffer, c.lib, c.origins, c.layouts = nil, nil, nil, nil, nil, nil
+	c.layoutBuffer = nil
 
 	c.data = make([]interface{}, 0)
+	c.ids = make([]int, 0)
 	c.buffer = make([]*interface{}, 0)
 	c.lib = make(map[string]int)
+	c.origins = make(map[int]string)
+	c.layouts = make(map[int]layout)
 }
 
 // SetAD for setting new Active Document index
@@ -50,9 +70,50 @@ func (c *state) GetAD() int {
 	return c.ad
 }
 
-// PushData for appending data to the data array
+// PushData for appending data to the data array. The data
+// gets a new id
 func (c *state) PushData(d interface{}) {
+	c.pushDataWithID(d, *c.nextID)
+	*c.nextID++
+}
+
+// pushDataWithID for appending data with a known id to the data array
+func (c *state) pushDataWithID(d interface{}, id int) {
 	c.data = append(c.data, d)
+	c.ids = append(c.ids, id)
+}
+
+// ReloadData replaces the data array with d. Documents keep the id
+// of the document that had the same index before the reload, while
+// names of documents that do not exist anymore are removed
+func (c *state) ReloadData(d []interface{}) {
+	ids := c.ids
+	c.data, c.ids = nil, make([]int, 0, len(d))
+
+	for i, j := range d {
+		if i < len(ids) {
+			c.data = append(c.data, j)
+			c.ids = append(c.ids, ids[i])
+			continue
+		}
+		c.PushData(j)
+	}
+
+	for i := len(c.ids); i < len(ids); i++ {
+		c.removeID(ids[i])
+		delete(c.origins, ids[i])
+		delete(c.layouts, ids[i])
+	}
+}
+
+// dropNilData removes the null documents from the data array
+// together with their ids, names, origins and layouts
+func (c *state) dropNilData() {
+	for i := len(c.data) - 1; i >= 0; i-- {
+		if c.data[i] == nil {
+			c.DeleteData(i)
+		}
+	}
 }
 
 // PushBuffer for appending data to the buffer array
@@ -118,15 +179,37 @@ func (c *state) SetBufferFromIndex(v interface{}, i int) error {
 
 // IndexInRange check if index is within data array range
 func (c *state) IndexInRange(i int) error {
-	if len(c.data)-1 >= i {
+	if i >= 0 && len(c.data)-1 >= i {
 		return nil
 	}
 	return fmt.Errorf(ire)
 }
 
-// Lib returns the lib map
+// indexOf returns the current index of the document with the given id
+func (c *state) indexOf(id int) (int, bool) {
+	for i, j := range c.ids {
+		if j == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Lib returns a map with the names of the documents
+// and their current index
 func (c *state) Lib() map[string]int {
-	return c.lib
+	index := make(map[int]int, len(c.ids))
+	for i, j := range c.ids {
+		index[j] = i
+	}
+
+	lib := make(map[string]int, len(c.lib))
+	for k, v := range c.lib {
+		if i, exists := index[v]; exists {
+			lib[k] = i
+		}
+	}
+	return lib
 }
 
 // addDoc for adding a document to the lib map
@@ -134,14 +217,17 @@ func (c *state) addDoc(k string, i int) error {
 	if err := c.IndexInRange(i); err != nil {
 		return wrapErr(err)
 	}
-	c.lib[k] = i
+	c.lib[strings.ToLower(k)] = c.ids[i]
 	return nil
 }
 
 // LibIndex returns the index for a given doc name
 func (c *state) LibIndex(doc string) (int, bool) {
-	i, exists := c.lib[strings.ToLower(doc)]
-	return i, exists
+	id, exists := c.lib[strings.ToLower(doc)]
+	if !exists {
+		return 0, false
+	}
+	return c.indexOf(id)
 }
 
 // RemoveDocName removes a doc from the lib
@@ -149,13 +235,18 @@ func (c *state) RemoveDocName(i int) error {
 	if err := c.IndexInRange(i); err != nil {
 		return wrapErr(err)
 	}
+	c.removeID(c.ids[i])
+
+	return nil
+}
+
+// removeID removes all names that point to the given id
+func (c *state) removeID(id int) {
 	for k, v := range c.lib {
-		if v == i {
+		if v == id {
 			delete(c.lib, k)
 		}
 	}
-
-	return nil
 }
 
 // DeleteData for deleting the i'th element from the data array
@@ -168,20 +259,114 @@ func (c *state) DeleteData(i int) error {
 		return wrapErr(err)
 	}
 
+	delete(c.origins, c.ids[i])
+	delete(c.layouts, c.ids[i])
 	c.data[i] = nil
 	c.data = append(c.data[:i], c.data[i+1:]...)
+	c.ids = append(c.ids[:i], c.ids[i+1:]...)
+
+	if c.ad > i || c.ad == i && c.ad > 0 {
+		c.ad = c.ad - 1
+	}
 
-	if c.ad == i {
-		if c.ad > 0 {
-			c.ad = c.ad - 1
-		} else {
-			c.ad = 0
+	return nil
+}
+
+// InsertData inserts d at the i'th position of the data array. The
+// data gets a new id. If i equals the length of the array, the data is
+// appended
+func (c *state) InsertData(d interface{}, i int) error {
+	if i != len(c.data) {
+		if err := c.IndexInRange(i); err != nil {
+			return wrapErr(err)
 		}
 	}
 
+	c.PushData(d)
+	return wrapErr(c.MoveData(len(c.data)-1, i))
+}
+
+// MoveData moves the f'th element of the data array to index t. The
+// active document index follows the document it was pointing to
+func (c *state) MoveData(f, t int) error {
+	if err := c.IndexInRange(f); err != nil {
+		return wrapErr(err)
+	}
+	if err := c.IndexInRange(t); err != nil {
+		return wrapErr(err)
+	}
+
+	id := -1
+	if c.IndexInRange(c.ad) == nil {
+		id = c.ids[c.ad]
+	}
+	data, dataID := c.data[f], c.ids[f]
+
+	c.data = append(c.data[:f], c.data[f+1:]...)
+	c.ids = append(c.ids[:f], c.ids[f+1:]...)
+
+	c.data = append(c.data[:t], append([]interface{}{data}, c.data[t:]...)...)
+	c.ids = append(c.ids[:t], append([]int{dataID}, c.ids[t:]...)...)
+
+	if i, exists := c.indexOf(id); exists {
+		c.ad = i
+	}
 	return nil
 }
 
+// DocName returns the first name (in lexical order) of the i'th document
+func (c *state) DocName(i int) (string, bool) {
+	if err := c.IndexInRange(i); err != nil {
+		return "", false
+	}
+
+	names := make([]string, 0)
+	for k, v := range c.lib {
+		if v == c.ids[i] {
+			names = append(names, k)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+
+	sort.Strings(names)
+	return names[0], true
+}
+
+// DocOrigin returns the file that the i'th document was imported from
+func (c *state) DocOrigin(i int) (string, bool) {
+	if err := c.IndexInRange(i); err != nil {
+		return "", false
+	}
+
+	o, exists := c.origins[c.ids[i]]
+	return o, exists
+}
+
+// setLayout records the layout of the i'th document from the
+// n'th entry of the layout buffer. If there is none, a layout that
+// was recorded before is removed
+func (c *state) setLayout(i, n int) {
+	if n < len(c.layoutBuffer) {
+		c.layouts[c.ids[i]] = c.layoutBuffer[n]
+		return
+	}
+	delete(c.layouts, c.ids[i])
+}
+
+// setOrigin records the file that the i'th document was imported from
+func (c *state) setOrigin(i int, o string) {
+	c.origins[c.ids[i]] = o
+}
+
+// RemoveName removes a name from the lib
+func (c *state) RemoveName(k string) bool {
+	_, exists := c.lib[strings.ToLower(k)]
+	delete(c.lib, strings.ToLower(k))
+	return exists
+}
+
 // // CopyBufferToData for copying buffer array over data array
 // func (c *state) CopyBufferToData() {
 // 	copy(c.data, c.buffer)
@@ -189,18 +374,23 @@ func (c *state) DeleteData(i int) error {
 
 // UnsetDataArray for deleting all data. This sets data = nil
 func (c *state) UnsetDataArray() {
-	c.data = nil
+	c.data, c.ids = nil, nil
 }
 
 // DeleteAllData calls PurgeAllData first and then creates a new empty array
 func (c *state) DeleteAllData() {
+	for _, j := range c.ids {
+		delete(c.origins, j)
+		delete(c.layouts, j)
+	}
 	c.UnsetDataArray()
 	c.data = make([]interface{}, 0)
+	c.ids = make([]int, 0)
 }
 
 // UnsetBufferArray This sets buffer = nil
 func (c *state) UnsetBufferArray() {
-	c.buffer = nil
+	c.buffer, c.layoutBuffer = nil, nil
 }
 
 // DeleteBuffer deletes the data from the buffer array
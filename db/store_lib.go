@@ -3,15 +3,16 @@ package db
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	e "github.com/ulfox/dby/errors"
-	"gopkg.in/yaml.v2"
 )
 
 type erf = func(e interface{}, p ...interface{}) error
@@ -23,23 +24,38 @@ var wrapErr erf = e.WrapErr
 type Storage struct {
 	sync.Mutex
 	*state
-	SQL  *SQL
-	Path string
-	mem  bool
+	SQL         *SQL
+	Path        string
+	mem         bool
+	keepEmpty   bool
+	codec       Codec
+	aliasPolicy AliasPolicy
+	resolver    *Resolver
+	schemas     []schemaRule
+	kube        *KubeValidator
+	labels      string
+	parent      *Storage
+	viewIDs     []int
 }
 
-// NewStorageFactory for creating a new Storage
+// NewStorageFactory for creating a new Storage. A string argument sets
+// the path of the local file, a bool sets in memory mode and a Codec
+// sets the file format instead of the one registered for the path's
+// extension
 func NewStorageFactory(p ...interface{}) (*Storage, error) {
 	var path string = "local/dby.yaml"
 	var inMem bool = true
+	var codec Codec
 
-	if len(p) > 0 {
-		switch i := p[0].(type) {
+	for _, j := range p {
+		switch i := j.(type) {
 		case string:
 			path = i
 			inMem = false
 		case bool:
 			inMem = i
+		case Codec:
+			codec = i
 		}
 	}
 
@@ -48,6 +64,7 @@ func NewStorageFactory(p ...interface{}) (*Storage, error) {
 		state: newStateFactory(),
 		Path:  path,
 		mem:   inMem,
+		codec: codec,
 	}
 
 	err := state.dbinit()
@@ -114,42 +131,20 @@ func (s *Storage) dbinit() error {
 // input(l) is the last path
 //
 // If a document has both paths, a name will be generated
-// and will be mapped with the document's index
+// and will be mapped with the document's index. It is the
+// same as SetNamesTemplate("{{.f}}/{{.l}}", CollisionOverwrite),
+// so a name that is generated twice points to the last document
 func (s *Storage) SetNames(f, l string) error {
-	for i := range s.GetAllData() {
-		s.SetAD(i)
-		kind, err := s.GetPath(strings.ToLower(f))
-		if err != nil {
-			continue
-		}
-		name, err := s.GetPath(strings.ToLower(l))
-		if err != nil {
-			continue
-		}
-
-		sKind, ok := kind.(string)
-		if !ok {
-			wrapErr(fieldNotString, strings.ToLower(f), kind)
-		}
-
-		sName, ok := name.(string)
-		if !ok {
-			wrapErr(fieldNotString, strings.ToLower(l), name)
-		}
-		err = s.addDoc(
+	return wrapErr(
+		s.SetNamesTemplate(
 			fmt.Sprintf(
-				"%s/%s",
-				strings.ToLower(sKind),
-				strings.ToLower(sName),
+				"{{.%s}}/{{.%s}}",
+				strings.ToLower(f),
+				strings.ToLower(l),
 			),
-			i,
-		)
-		if err != nil {
-			return wrapErr(err)
-		}
-	}
-
-	return nil
+			CollisionOverwrite,
+		),
+	)
 }
 
 // SetName adds a name for a document and maps with it the given doc index
@@ -166,13 +161,19 @@ func (s *Storage) SetName(n string, i int) error {
 	return nil
 }
 
-// DeleteDoc will the document with the given index
+// DeleteDoc will the document with the given index.
+// The local file is not changed until the next Write.
+// For views, the document is also removed from the parent
 func (s *Storage) DeleteDoc(i int) error {
 	err := s.DeleteData(i)
 	if err != nil {
 		return wrapErr(err)
 	}
 
+	if s.parent != nil {
+		return wrapErr(s.sync())
+	}
+
 	return nil
 }
 
@@ -188,20 +189,98 @@ func (s *Storage) Switch(i int) error {
 // AddDoc will add a new document to the stack and will switch
 // Active Document index to that document
 func (s *Storage) AddDoc() error {
+	ad := s.GetAD()
 	s.PushData(emptyMap())
 	s.SetAD(len(s.GetAllData()) - 1)
-	return s.stateReload()
+	if err := s.stateReload(); err != nil {
+		s.SetAD(ad)
+		return wrapErr(err)
+	}
+	return nil
+}
+
+// DocRef holds the name of a document along with the
+// document's index
+type DocRef struct {
+	Name  string
+	Index int
 }
 
-// ListDocs will return an array with all docs names
-func (s *Storage) ListDocs() []string {
-	var docs []string
-	for i := range s.Lib() {
-		docs = append(docs, i)
+// ListDocs will return an array with all docs names and their
+// index. The array is sorted by index. Docs with more than one
+// name appear once for each name
+func (s *Storage) ListDocs() []DocRef {
+	var docs []DocRef
+	for k, v := range s.Lib() {
+		docs = append(docs, DocRef{Name: k, Index: v})
 	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Index != docs[j].Index {
+			return docs[i].Index < docs[j].Index
+		}
+		return docs[i].Name < docs[j].Name
+	})
+
 	return docs
 }
 
+// RenameDoc changes the name of a document from o to n
+func (s *Storage) RenameDoc(o, n string) error {
+	i, exists := s.LibIndex(o)
+	if !exists {
+		return wrapErr(docNotExists, strings.ToLower(o))
+	}
+	if _, exists := s.LibIndex(n); exists {
+		return wrapErr(docExists, strings.ToLower(n))
+	}
+
+	s.RemoveName(o)
+	return wrapErr(s.addDoc(n, i))
+}
+
+// UnnameDoc removes a name from the lib. The document is not removed
+func (s *Storage) UnnameDoc(n string) error {
+	if !s.RemoveName(n) {
+		return wrapErr(docNotExists, strings.ToLower(n))
+	}
+	return nil
+}
+
+// DeleteDocByName will delete the document with the given name
+func (s *Storage) DeleteDocByName(n string) error {
+	i, exists := s.LibIndex(n)
+	if !exists {
+		return wrapErr(docNotExists, strings.ToLower(n))
+	}
+
+	return wrapErr(s.DeleteDoc(i))
+}
+
+// MoveDoc moves the document with index f to index t. Document
+// names and Active Document keep pointing to the same documents
+func (s *Storage) MoveDoc(f, t int) error {
+	err := s.MoveData(f, t)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.stateReload()
+}
+
+// InsertDoc will add a new document at the given index and will
+// switch Active Document index to that document. Documents from that
+// index and after are shifted by one
+func (s *Storage) InsertDoc(i int) error {
+	err := s.InsertData(emptyMap(), i)
+	if err != nil {
+		return wrapErr(err)
+	}
+	s.SetAD(i)
+
+	return s.stateReload()
+}
+
 // SwitchDoc for switching to a document using the documents name (if any)
 func (s *Storage) SwitchDoc(n string) error {
 	i, exists := s.LibIndex(n)
@@ -221,35 +300,24 @@ func (s *Storage) DeleteAll(delete bool) *Storage {
 	return s
 }
 
-// ImportDocs for importing documents
+// ImportDocs for importing documents. Files are decoded by the
+// Codec that is registered for their extension. See CodecFor
 func (s *Storage) ImportDocs(path string, o ...bool) error {
-	impf, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return wrapErr(err)
 	}
+	defer f.Close()
 
-	var counter int
-	var data interface{}
-	s.UnsetBufferArray()
-
-	dec := yaml.NewDecoder(bytes.NewReader(impf))
-	for {
-		err = dec.Decode(&data)
-		if err == nil {
-			s.PushBuffer(data)
-			data = nil
-
-			counter++
-			continue
-		}
+	return wrapErr(s.importFrom(f, CodecFor(path), o...))
+}
 
-		if err.Error() == "EOF" {
-			break
-		}
-		s.UnsetBufferArray()
+func (s *Storage) importFrom(r io.Reader, c Codec, o ...bool) error {
+	if err := s.readBufferWith(r, c); err != nil {
 		return wrapErr(err)
 	}
 
+	prevData, prevIDs, prevLib := s.data, s.ids, s.lib
 	if len(o) > 0 {
 		issueWarning(deprecatedFeature, "ImportDocs(string, bool)", "Storage.DeleteAll(true).ImportDocs(path)")
 		if o[0] {
@@ -258,16 +326,47 @@ func (s *Storage) ImportDocs(path string, o ...bool) error {
 		}
 	}
 
-	for _, j := range s.GetAllBuffer() {
+	docs := len(s.GetAllData())
+	s.pushBuffer("")
+
+	return s.commitImport(docs, prevData, prevIDs, prevLib)
+}
+
+// pushBuffer appends the documents of the buffer array to the
+// data array and records o as their origin if it is not empty.
+// Empty documents are skipped unless KeepEmptyDocs is set
+func (s *Storage) pushBuffer(o string) {
+	for n, j := range s.GetAllBuffer() {
 		if j == nil {
 			continue
 		}
-		if len((*j).(map[interface{}]interface{})) == 0 {
+		if !s.keepEmpty && isEmptyDoc(*j) {
 			continue
 		}
 		s.PushData(*j)
+		s.setLayout(len(s.GetAllData())-1, n)
+		if o != "" {
+			s.setOrigin(len(s.GetAllData())-1, o)
+		}
 	}
 	s.UnsetBufferArray()
+}
+
+// commitImport validates the documents that were imported after
+// index docs. On failure, the data, ids and lib are restored
+func (s *Storage) commitImport(docs int, data []interface{}, ids []int, lib map[string]int) error {
+	var violations []Violation
+	for i := docs; i < len(s.GetAllData()); i++ {
+		violations = append(violations, s.validateDoc(i)...)
+	}
+	if len(violations) > 0 {
+		for _, j := range s.ids[docs:] {
+			delete(s.origins, j)
+		}
+		s.data, s.ids, s.lib = data, ids, lib
+		return wrapErr(&ValidationError{Violations: violations})
+	}
+
 	return s.stateReload()
 }
 
@@ -277,9 +376,28 @@ func (s *Storage) InMem(m bool) *Storage {
 	return s
 }
 
-// Read for reading the local yaml file and importing it
-// in memory
+// KeepEmptyDocs for configuring db to keep empty documents (null
+// or {}) on import and null documents on write. By default, empty
+// documents are dropped
+func (s *Storage) KeepEmptyDocs(k bool) *Storage {
+	s.keepEmpty = k
+	return s
+}
+
+// Read for reading the local file and importing it in memory.
+// The file is decoded by the Storage's Codec. For views, the parent is read and the view's
+// documents are reloaded from the parent
 func (s *Storage) Read() error {
+	if s.parent != nil {
+		if !s.parent.mem {
+			if err := s.parent.Read(); err != nil {
+				return wrapErr(err)
+			}
+		}
+		s.refresh()
+		return nil
+	}
+
 	f, err := ioutil.ReadFile(s.Path)
 	if err != nil {
 		return wrapErr(err)
@@ -288,81 +406,97 @@ func (s *Storage) Read() error {
 	s.Lock()
 	defer s.Unlock()
 
-	s.UnsetBufferArray()
-
-	var data interface{}
-	dec := yaml.NewDecoder(bytes.NewReader(f))
-	for {
-		err := dec.Decode(&data)
-		if err == nil {
-			s.PushBuffer(data)
-			data = nil
-			continue
-		}
-
-		if err.Error() == "EOF" {
-			break
-		}
-		s.UnsetBufferArray()
+	if err := s.readBufferWith(bytes.NewReader(f), s.fileCodec()); err != nil {
 		return wrapErr(err)
 	}
 
-	s.UnsetDataArray()
-
+	docs := make([]interface{}, 0, len(s.GetAllBuffer()))
 	for _, j := range s.GetAllBuffer() {
 		if j == nil {
 			continue
 		}
-		s.PushData(*j)
+		docs = append(docs, *j)
 	}
+	// Null documents are not written unless KeepEmptyDocs is set,
+	// so the ids of the documents that follow them must not shift
+	if !s.keepEmpty {
+		s.dropNilData()
+	}
+	s.ReloadData(docs)
+	for i := range s.GetAllData() {
+		s.setLayout(i, i)
+	}
+
 	s.UnsetBufferArray()
 	return nil
 }
 
-// Write for writing memory content to the local yaml file
+// Write for writing memory content to the local file.
+// For views, the view's documents are copied to the parent
+// and the parent is written
 func (s *Storage) Write() error {
+	if s.parent != nil {
+		if err := s.sync(); err != nil {
+			return wrapErr(err)
+		}
+		return wrapErr(s.parent.Write())
+	}
+
+	if s.Path == "" {
+		return wrapErr(noFile)
+	}
+
 	s.Lock()
 	defer s.Unlock()
 
+	var buf bytes.Buffer
+	if err := s.writeDocsWith(&buf, s.allDocs(), s.fileCodec()); err != nil {
+		return wrapErr(err)
+	}
+
 	wrkDir := path.Dir(s.Path)
 	f, err := ioutil.TempFile(wrkDir, ".tx.*")
 	if err != nil {
 		return wrapErr(err)
 	}
 
-	var buf bytes.Buffer
-	enc := yaml.NewEncoder(&buf)
-
-	for _, j := range s.GetAllData() {
-		if j == nil {
-			continue
-		}
-
-		err := enc.Encode(j)
-		if err != nil {
-			return wrapErr(err)
-		}
-	}
-
 	_, err = f.Write(buf.Bytes())
 	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
 		return wrapErr(err)
 	}
 	err = f.Close()
 	if err != nil {
+		os.Remove(f.Name())
 		return wrapErr(err)
 	}
 
-	return wrapErr(os.Rename(f.Name(), s.Path))
+	err = os.Rename(f.Name(), s.Path)
+	if err != nil {
+		os.Remove(f.Name())
+		return wrapErr(err)
+	}
+
+	return nil
 }
 
 func (s *Storage) stateReload() error {
+	if s.parent != nil {
+		return s.reloadParent()
+	}
+
 	if s.mem {
 		return nil
 	}
 
 	err := s.Write()
 	if err != nil {
+		// The file still holds the last written state,
+		// so read it back to drop the failed change
+		if s.Read() == nil && s.IndexInRange(s.GetAD()) != nil {
+			s.ad = 0
+		}
 		return wrapErr(err)
 	}
 
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOMLCodec reads and writes toml files. A toml file
+// holds a single document, which must be a map
+type TOMLCodec struct{}
+
+// Decode for decoding the toml document from r
+func (TOMLCodec) Decode(r io.Reader) ([]interface{}, error) {
+	data := make(map[string]interface{})
+	if _, err := toml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return []interface{}{fromStringKeys(data)}, nil
+}
+
+// Encode for encoding the document of docs to w
+func (TOMLCodec) Encode(w io.Writer, docs []interface{}) error {
+	doc, err := singleDoc("toml", docs)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	obj, isMap := stringKeys(doc).(map[string]interface{})
+	if !isMap {
+		return wrapErr(notAMap)
+	}
+
+	return wrapErr(toml.NewEncoder(w).Encode(obj))
+}
@@ -18,9 +18,13 @@ func checkKeyPath(k []string) error {
 }
 
 func copyMap(o interface{}) (interface{}, error) {
-	obj, err := interfaceToMap(o)
-	if err != nil {
-		return nil, wrapErr(err)
+	var obj interface{} = o
+	if _, isArray := o.([]interface{}); !isArray {
+		m, err := interfaceToMap(o)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		obj = m
 	}
 
 	var cache interface{}
@@ -53,6 +57,15 @@ func interfaceToMap(o interface{}) (map[interface{}]interface{}, error) {
 	return obj, nil
 }
 
+// isEmptyDoc reports if a document is null or an empty map
+func isEmptyDoc(o interface{}) bool {
+	if o == nil {
+		return true
+	}
+	obj, isMap := o.(map[interface{}]interface{})
+	return isMap && len(obj) == 0
+}
+
 func deleteMap(o interface{}) {
 	for kn := range o.(map[interface{}]interface{}) {
 		delete(o.(map[interface{}]interface{}), kn)
@@ -87,3 +100,86 @@ func fileExists(filepath string) (bool, error) {
 
 	return true, nil
 }
+
+// addNumbers returns the sum of two numeric values. If any of the
+// two values is a float, the result is a float64, otherwise an int
+func addNumbers(a, b interface{}) (interface{}, error) {
+	var af, bf float64
+	var ai, bi int
+	var isFloat bool
+
+	for _, j := range []struct {
+		v interface{}
+		i *int
+		f *float64
+	}{{a, &ai, &af}, {b, &bi, &bf}} {
+		switch n := j.v.(type) {
+		case int:
+			*j.i, *j.f = n, float64(n)
+		case int64:
+			*j.i, *j.f = int(n), float64(n)
+		case uint64:
+			*j.i, *j.f = int(n), float64(n)
+		case float32:
+			*j.f = float64(n)
+			isFloat = true
+		case float64:
+			*j.f = n
+			isFloat = true
+		default:
+			return nil, wrapErr(notANumber, j.v)
+		}
+	}
+
+	if isFloat {
+		return af + bf, nil
+	}
+	return ai + bi, nil
+}
+
+// stringKeys returns a copy of the given object where all
+// map[interface{}]interface{} objects have been converted
+// to map[string]interface{}
+func stringKeys(o interface{}) interface{} {
+	switch obj := o.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			m[k] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for i, v := range obj {
+			a[i] = stringKeys(v)
+		}
+		return a
+	}
+	return o
+}
+
+// deepCopy returns a copy of the given object. Maps and
+// arrays are copied recursively
+func deepCopy(o interface{}) interface{} {
+	switch obj := o.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(obj))
+		for k, v := range obj {
+			m[k] = deepCopy(v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for i, v := range obj {
+			a[i] = deepCopy(v)
+		}
+		return a
+	}
+	return o
+}
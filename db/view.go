@@ -0,0 +1,165 @@
+package db
+
+import (
+	"reflect"
+	"sort"
+)
+
+// DocFilter returns the indexes of the documents of a Storage
+// that should be part of a view
+type DocFilter func(s *Storage) ([]int, error)
+
+// ByNames is a DocFilter that matches the documents with the given names
+func ByNames(n ...string) DocFilter {
+	return func(s *Storage) ([]int, error) {
+		docs := make([]int, 0, len(n))
+		for _, j := range n {
+			i, exists := s.LibIndex(j)
+			if !exists {
+				return nil, wrapErr(docNotExists, j)
+			}
+			docs = append(docs, i)
+		}
+		return docs, nil
+	}
+}
+
+// BySelector is a DocFilter that matches the documents
+// with labels that match the given label selector
+func BySelector(sel string) DocFilter {
+	return func(s *Storage) ([]int, error) {
+		return s.Select(sel)
+	}
+}
+
+// ByPath is a DocFilter that matches the documents that have the
+// given path set to v, e.g. ByPath("kind", "Deployment")
+func ByPath(k string, v interface{}) DocFilter {
+	return func(s *Storage) ([]int, error) {
+		docs := make([]int, 0)
+		for i, j := range s.GetPathGlobal(k) {
+			if reflect.DeepEqual(j, v) {
+				docs = append(docs, i)
+			}
+		}
+		sort.Ints(docs)
+		return docs, nil
+	}
+}
+
+// View returns a Storage that only sees the documents that match the
+// filter. The view exposes the same API as its parent, but has its own
+// Active Document and its own document indexes. Documents that are
+// changed, added or deleted through the view are changed, added or
+// deleted in the parent, and Write on the view writes the parent.
+// The view starts with the schemas of the parent, while schemas that
+// are set or unset on the view only apply to the view
+func (s *Storage) View(f DocFilter) (*Storage, error) {
+	docs, err := f(s)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	v := &Storage{
+		SQL:         NewSQLFactory(),
+		state:       newStateFactory(),
+		Path:        s.Path,
+		mem:         s.mem,
+		keepEmpty:   s.keepEmpty,
+		aliasPolicy: s.aliasPolicy,
+		resolver:    s.resolver,
+		schemas:     append([]schemaRule(nil), s.schemas...),
+		kube:        s.kube,
+		labels:      s.labels,
+		parent:      s,
+	}
+	v.nextID, v.lib, v.origins, v.layouts = s.nextID, s.lib, s.origins, s.layouts
+
+	v.viewIDs = make([]int, 0, len(docs))
+	seen := make(map[int]bool, len(docs))
+	for _, i := range docs {
+		if err := s.IndexInRange(i); err != nil || seen[i] {
+			continue
+		}
+		seen[i] = true
+		v.viewIDs = append(v.viewIDs, s.ids[i])
+	}
+	v.refresh()
+
+	return v, nil
+}
+
+// Parent returns the Storage that a view was created from or
+// nil if the Storage is not a view
+func (s *Storage) Parent() *Storage {
+	return s.parent
+}
+
+// refresh loads the view's documents from its parent. Documents
+// that do not exist in the parent anymore are removed from the view
+func (s *Storage) refresh() {
+	id := -1
+	if s.IndexInRange(s.GetAD()) == nil {
+		id = s.ids[s.GetAD()]
+	}
+
+	s.data, s.ids = make([]interface{}, 0, len(s.viewIDs)), make([]int, 0, len(s.viewIDs))
+	ids := make([]int, 0, len(s.viewIDs))
+	for _, j := range s.viewIDs {
+		i, exists := s.parent.indexOf(j)
+		if !exists {
+			continue
+		}
+		s.pushDataWithID(s.parent.data[i], j)
+		ids = append(ids, j)
+	}
+	s.viewIDs = ids
+
+	s.ad = 0
+	if i, exists := s.indexOf(id); exists {
+		s.ad = i
+	}
+}
+
+// sync copies the view's documents to its parent. Documents that
+// were added to the view are appended to the parent, while documents
+// that were deleted from the view are deleted from the parent
+func (s *Storage) sync() error {
+	for _, j := range s.viewIDs {
+		if _, exists := s.indexOf(j); exists {
+			continue
+		}
+		if i, exists := s.parent.indexOf(j); exists {
+			if err := s.parent.DeleteData(i); err != nil {
+				return wrapErr(err)
+			}
+		}
+	}
+
+	for i, j := range s.ids {
+		k, exists := s.parent.indexOf(j)
+		if !exists {
+			s.parent.pushDataWithID(s.data[i], j)
+			continue
+		}
+		s.parent.data[k] = s.data[i]
+	}
+
+	s.viewIDs = append(make([]int, 0, len(s.ids)), s.ids...)
+	return nil
+}
+
+// reloadParent syncs the view with its parent, reloads the parent's
+// state and then refreshes the view
+func (s *Storage) reloadParent() error {
+	if err := s.sync(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := s.parent.stateReload(); err != nil {
+		return wrapErr(err)
+	}
+
+	s.refresh()
+	return nil
+}
@@ -1,36 +1,55 @@
 package db
 
 import (
+	"reflect"
+	"sort"
 	"strings"
 )
 
 // Upsert is a SQL wrapper for adding/updating map structures
 func (s *Storage) Upsert(k string, i interface{}) error {
+	if err := s.checkAliased(s.GetAD(), k); err != nil {
+		return wrapErr(err)
+	}
+	b := s.backup(s.GetAD())
+
 	data, err := s.SQL.toInterfaceMap(i)
 	if err != nil {
 		return wrapErr(err)
 	}
-	err = s.SQL.upsertRecursive(strings.Split(k, "."), s.GetData(), data)
+	err = s.SQL.upsertRecursive(strings.Split(k, "."), s.upsertTarget(), data)
 	if err != nil {
 		return wrapErr(err)
 	}
 
-	return s.stateReload()
+	return s.commit(b)
 }
 
 // UpsertGlobal is a SQL wrapper for adding/updating map structures
 // in all documents. This will change all existing paths to the given
-// structure and add new if the path is missing for a document
-func (s *Storage) UpsertGlobal(k string, i interface{}) error {
+// structure and add new if the path is missing for a document.
+// An optional label selector limits the documents that are changed
+func (s *Storage) UpsertGlobal(k string, i interface{}, sel ...string) error {
+	docs, err := s.selectDocs(sel)
+	if err != nil {
+		return wrapErr(err)
+	}
+	for _, j := range docs {
+		if err := s.checkAliased(j, k); err != nil {
+			return wrapErr(err)
+		}
+	}
+	b := s.backup(docs...)
+
 	data, err := s.SQL.toInterfaceMap(i)
 	if err != nil {
 		return wrapErr(err)
 	}
 
 	c := s.GetAD()
-	for j := range s.GetAllData() {
+	for _, j := range docs {
 		s.SetAD(j)
-		err := s.SQL.upsertRecursive(strings.Split(k, "."), s.GetData(), data)
+		err := s.SQL.upsertRecursive(strings.Split(k, "."), s.upsertTarget(), data)
 		if err != nil {
 			return wrapErr(err)
 		}
@@ -38,23 +57,35 @@ func (s *Storage) UpsertGlobal(k string, i interface{}) error {
 
 	s.SetAD(c)
 
-	return s.stateReload()
+	return s.commit(b)
 }
 
 // UpdateGlobal is a SQL wrapper for adding/updating map structures
 // in all documents. This will change all existing paths to the given
-// structure (if any)
-func (s *Storage) UpdateGlobal(k string, i interface{}) error {
+// structure (if any). An optional label selector limits the
+// documents that are changed
+func (s *Storage) UpdateGlobal(k string, i interface{}, sel ...string) error {
+	docs, err := s.selectDocs(sel)
+	if err != nil {
+		return wrapErr(err)
+	}
+	for _, j := range docs {
+		if err := s.checkAliased(j, k); err != nil {
+			return wrapErr(err)
+		}
+	}
+	b := s.backup(docs...)
+
 	data, err := s.SQL.toInterfaceMap(i)
 	if err != nil {
 		return wrapErr(err)
 	}
 
 	c := s.GetAD()
-	for j := range s.GetAllData() {
+	for _, j := range docs {
 		s.SetAD(j)
 
-		if _, err := s.GetPath(k); err != nil {
+		if _, err := s.GetPathRaw(k); err != nil {
 			continue
 		}
 
@@ -66,13 +97,24 @@ func (s *Storage) UpdateGlobal(k string, i interface{}) error {
 
 	s.SetAD(c)
 
-	return s.stateReload()
+	return s.commit(b)
 }
 
 // GetFirst is a SQL wrapper for finding the first key in the
 // yaml hierarchy. If two keys are on the same level but under
-// different paths, then the selection will be random
+// different paths, then the selection will be random.
+// References are expanded if a Resolver is set
 func (s *Storage) GetFirst(k string) (interface{}, error) {
+	obj, err := s.GetFirstRaw(k)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return s.resolve(s.GetAD(), obj)
+}
+
+// GetFirstRaw works like GetFirst but never expands references
+func (s *Storage) GetFirstRaw(k string) (interface{}, error) {
 	dat := s.GetData()
 	obj, err := s.SQL.getFirst(k, &dat)
 	if err != nil {
@@ -84,12 +126,16 @@ func (s *Storage) GetFirst(k string) (interface{}, error) {
 
 // GetFirstGlobal does the same as GetFirst but for all docs.
 // Instead of returning an interface it returns a map with keys
-// the index of the doc that a key was found and value the value of the key
-func (s *Storage) GetFirstGlobal(k string) map[int]interface{} {
+// the index of the doc that a key was found and value the value of the key.
+// An optional label selector limits the documents that are queried. If the
+// selector is not valid, the map is empty. Documents whose value can not
+// be resolved are skipped
+func (s *Storage) GetFirstGlobal(k string, sel ...string) map[int]interface{} {
 	found := make(map[int]interface{})
+	docs, _ := s.selectDocs(sel)
 
 	c := s.GetAD()
-	for j := range s.GetAllData() {
+	for _, j := range docs {
 		s.SetAD(j)
 		dat := s.GetData()
 
@@ -97,7 +143,11 @@ func (s *Storage) GetFirstGlobal(k string) map[int]interface{} {
 		if err != nil {
 			continue
 		}
-		found[j] = *obj
+		v, err := s.resolve(j, *obj)
+		if err != nil {
+			continue
+		}
+		found[j] = v
 	}
 
 	s.SetAD(c)
@@ -134,12 +184,15 @@ func (s *Storage) FindKeys(k string) ([]string, error) {
 
 // FindKeysGlobal does the same as FindKeys but for all docs.
 // Instead of returning a list of keys it returns a map with indexes
-// from the docs and value an array of paths that was found
-func (s *Storage) FindKeysGlobal(k string) map[int][]string {
+// from the docs and value an array of paths that was found. An optional
+// label selector limits the documents that are queried. If the selector
+// is not valid, the map is empty
+func (s *Storage) FindKeysGlobal(k string, sel ...string) map[int][]string {
 	found := make(map[int][]string)
+	docs, _ := s.selectDocs(sel)
 
 	c := s.GetAD()
-	for j := range s.GetAllData() {
+	for _, j := range docs {
 		s.SetAD(j)
 		dat := s.GetData()
 
@@ -158,11 +211,22 @@ func (s *Storage) FindKeysGlobal(k string) map[int][]string {
 // GetPath is a SQL wrapper that returns the value for a given
 // path. Example, it would return "value-1" if "key-1.key-2" was
 // the path asked from the following yaml
-// ---------
-// key-1:
-//	key-2: value-1
 //
+//	key-1:
+//	  key-2: value-1
+//
+// References are expanded if a Resolver is set
 func (s *Storage) GetPath(k string) (interface{}, error) {
+	obj, err := s.GetPathRaw(k)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return s.resolve(s.GetAD(), obj)
+}
+
+// GetPathRaw works like GetPath but never expands references
+func (s *Storage) GetPathRaw(k string) (interface{}, error) {
 	keys := strings.Split(k, ".")
 	dat := s.GetData()
 	obj, err := s.SQL.getPath(keys, &dat)
@@ -174,20 +238,27 @@ func (s *Storage) GetPath(k string) (interface{}, error) {
 }
 
 // GetPathGlobal does the same as GetPath but globally for all
-// docs
-func (s *Storage) GetPathGlobal(k string) map[int]interface{} {
+// docs. An optional label selector limits the documents that are
+// queried. If the selector is not valid, the map is empty. Documents
+// whose value can not be resolved are skipped
+func (s *Storage) GetPathGlobal(k string, sel ...string) map[int]interface{} {
 	found := make(map[int]interface{})
 	keys := strings.Split(k, ".")
+	docs, _ := s.selectDocs(sel)
 
 	c := s.GetAD()
-	for j := range s.GetAllData() {
+	for _, j := range docs {
 		s.SetAD(j)
 		dat := s.GetData()
 		obj, err := s.SQL.getPath(keys, &dat)
 		if err != nil {
 			continue
 		}
-		found[j] = *obj
+		v, err := s.resolve(j, *obj)
+		if err != nil {
+			continue
+		}
+		found[j] = v
 	}
 
 	s.SetAD(c)
@@ -200,6 +271,8 @@ func (s *Storage) GetPathGlobal(k string) map[int]interface{} {
 // validate that the path exists, then it would export the value of
 // GetPath("key-1.key-2") and delete the object that matches key-3
 func (s *Storage) Delete(k string) error {
+	b := s.backup(s.GetAD())
+
 	dat := s.GetData()
 
 	err := s.SQL.delPath(k, &dat)
@@ -207,28 +280,261 @@ func (s *Storage) Delete(k string) error {
 		return wrapErr(err)
 	}
 
-	return s.stateReload()
+	err = s.SetData(dat)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
 }
 
 // DeleteGlobal is the same as Delete but will try to delete
-// the path on all docs (if found)
-func (s *Storage) DeleteGlobal(k string) error {
-	dat := s.GetData()
-	err := s.SQL.delPath(k, &dat)
+// the path on all docs (if found). Docs that miss the path are
+// skipped, while an error is returned if none of the docs has it.
+// An optional label selector limits the documents that are changed
+func (s *Storage) DeleteGlobal(k string, sel ...string) error {
+	docs, err := s.selectDocs(sel)
 	if err != nil {
 		return wrapErr(err)
 	}
+	b := s.backup(docs...)
+
+	found := false
+	keys := strings.Split(k, ".")
+	for _, j := range docs {
+		dat, err := s.GetDataFromIndex(j)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		if _, err := s.SQL.getPath(keys, &dat); err != nil {
+			continue
+		}
 
-	return s.stateReload()
+		err = s.SQL.delPath(k, &dat)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		err = s.SetDataFromIndex(dat, j)
+		if err != nil {
+			return wrapErr(err)
+		}
+		found = true
+	}
+
+	if !found {
+		return wrapErr(keyDoesNotExist, k)
+	}
+
+	return s.commit(b)
 }
 
 // MergeDBs is a SQL wrapper that merges a source yaml file
 // with the DBy local yaml file.
 func (s *Storage) MergeDBs(path string) error {
-	err := s.SQL.mergeDBs(path, s.GetData())
+	b := s.backup(s.GetAD())
+
+	err := s.SQL.mergeDBs(path, CodecFor(path), s.upsertTarget())
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
+}
+
+// Increment is a SQL wrapper for adding a delta to a numeric value.
+// If the path does not exist, it is created with the delta as its
+// value, the same way Upsert creates missing paths. Int values that
+// are incremented by a float delta become floats
+func (s *Storage) Increment(k string, d interface{}) error {
+	if err := s.checkAliased(s.GetAD(), k); err != nil {
+		return wrapErr(err)
+	}
+	b := s.backup(s.GetAD())
+
+	dat := s.GetData()
+
+	v := d
+	obj, err := s.SQL.getPath(strings.Split(k, "."), &dat)
+	if err == nil {
+		v, err = addNumbers(*obj, d)
+		if err != nil {
+			return wrapErr(err)
+		}
+	} else if _, err := addNumbers(0, d); err != nil {
+		return wrapErr(err)
+	}
+
+	err = s.SQL.upsertRecursive(strings.Split(k, "."), s.upsertTarget(), v)
 	if err != nil {
 		return wrapErr(err)
 	}
 
-	return s.stateReload()
+	return s.commit(b)
+}
+
+// Append is a SQL wrapper for adding values at the end of an array.
+// If the path does not exist, it is created with an array that
+// holds the given values
+func (s *Storage) Append(k string, v ...interface{}) error {
+	return s.extendArray(k, false, v...)
+}
+
+// Prepend works like Append but adds the values at the beginning
+// of the array
+func (s *Storage) Prepend(k string, v ...interface{}) error {
+	return s.extendArray(k, true, v...)
+}
+
+func (s *Storage) extendArray(k string, front bool, v ...interface{}) error {
+	if err := s.checkAliased(s.GetAD(), k); err != nil {
+		return wrapErr(err)
+	}
+	b := s.backup(s.GetAD())
+
+	values := make([]interface{}, 0, len(v))
+	for _, j := range v {
+		data, err := s.SQL.toInterfaceMap(j)
+		if err != nil {
+			return wrapErr(err)
+		}
+		values = append(values, data)
+	}
+
+	dat := s.GetData()
+	array := make([]interface{}, 0)
+	obj, err := s.SQL.getPath(strings.Split(k, "."), &dat)
+	if err == nil {
+		if getObjectType(*obj) != arrayObj {
+			return wrapErr(notArrayObj)
+		}
+		array = (*obj).([]interface{})
+	}
+
+	if front {
+		array = append(values, array...)
+	} else {
+		array = append(array, values...)
+	}
+
+	err = s.SQL.upsertRecursive(strings.Split(k, "."), s.upsertTarget(), array)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
+}
+
+// UpsertMany is a SQL wrapper for adding/updating several paths at once.
+// All paths and values are validated before any change is done and the
+// state is written only once. Paths are applied in lexical order, so a
+// parent path is always set before any of its children
+func (s *Storage) UpsertMany(m map[string]interface{}) error {
+	b := s.backup(s.GetAD())
+
+	keys := make([]string, 0, len(m))
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if err := checkKeyPath(strings.Split(k, ".")); err != nil {
+			return wrapErr(err)
+		}
+		if err := s.checkAliased(s.GetAD(), k); err != nil {
+			return wrapErr(err)
+		}
+
+		data, err := s.SQL.toInterfaceMap(v)
+		if err != nil {
+			return wrapErr(err)
+		}
+		keys = append(keys, k)
+		values[k] = data
+	}
+	sort.Strings(keys)
+
+	dat, err := copyMap(s.GetData())
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	for _, k := range keys {
+		err := s.SQL.upsertRecursive(strings.Split(k, "."), dat, values[k])
+		if err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	err = s.SetData(dat)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
+}
+
+// DeleteMany is a SQL wrapper for deleting several paths at once. All
+// paths must exist, otherwise nothing is deleted. The state is written
+// only once
+func (s *Storage) DeleteMany(k []string) error {
+	b := s.backup(s.GetAD())
+
+	dat, err := copyMap(s.GetData())
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	for _, j := range k {
+		if _, err := s.SQL.getPath(strings.Split(j, "."), &dat); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	for _, j := range k {
+		if err := s.SQL.delPath(j, &dat); err != nil {
+			return wrapErr(err)
+		}
+	}
+
+	err = s.SetData(dat)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return s.commit(b)
+}
+
+// GetPathInto is a SQL wrapper that decodes the value of a given
+// path into the value pointed by out. Struct fields are matched
+// using their yaml tags
+func (s *Storage) GetPathInto(k string, out interface{}) error {
+	obj, err := s.GetPath(k)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	return wrapErr(NewConvertFactory().Input(obj).Decode(out))
+}
+
+// UpsertStruct is a SQL wrapper for adding/updating a path with
+// the content of a struct. Struct fields are encoded using their
+// yaml tags
+func (s *Storage) UpsertStruct(k string, i interface{}) error {
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return wrapErr(notAType, "struct")
+	}
+
+	return s.Upsert(k, v.Interface())
+}
+
+// upsertTarget returns the Active Document. A null document is
+// replaced by an empty map first, so paths can be upserted into it
+func (s *Storage) upsertTarget() interface{} {
+	if s.GetData() == nil {
+		s.SetData(emptyMap())
+	}
+	return s.GetData()
 }
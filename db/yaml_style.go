@@ -0,0 +1,662 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// QuoteStyle sets how the YAMLCodec quotes string values
+type QuoteStyle int
+
+const (
+	// QuoteAuto quotes strings only when they can not be written plain
+	QuoteAuto QuoteStyle = iota
+	// QuoteSingle single quotes all string values
+	QuoteSingle
+	// QuoteDouble double quotes all string values
+	QuoteDouble
+)
+
+// YAMLStyle configures the output of the YAMLCodec. The zero
+// YAMLStyle keeps the default yaml.v2 output
+type YAMLStyle struct {
+	// Indent is the number of spaces of each indentation
+	// level. Values lower than 2 default to 2
+	Indent int
+	// IndentSequences indents sequences that are map values
+	// (key:\n  - item) instead of writing them at the key's
+	// indentation (key:\n- item)
+	IndentSequences bool
+	// KeepKeyOrder writes map keys in the order they had in the
+	// local file. Keys that were added since the last Read are written
+	// after them in lexical order. Keys are sorted otherwise
+	KeepKeyOrder bool
+	// KeepAnchors writes anchors, aliases and merge keys (<<) as they
+	// were in the local file and implies KeepKeyOrder. Aliases and merged
+	// keys whose value no longer matches their anchor are written in full
+	KeepAnchors bool
+	// Quote sets how string values are quoted. Keys and strings
+	// that have line breaks are quoted only when needed
+	Quote QuoteStyle
+	// LineWidth folds strings that do not fit in the given width
+	// on spaces. 0 disables folding
+	LineWidth int
+	// DocumentStart writes --- before each document, including
+	// the first one
+	DocumentStart bool
+}
+
+// layout holds what the yaml decoder drops from a document: the order
+// of map keys, anchors, aliases and merge keys (<<). Nodes are addressed
+// by their path, e.g. "spec.containers.[0]"
+type layout struct {
+	keys    map[string][]string // keys of each map in their order
+	anchors map[string]string   // anchor of each anchored node
+	paths   map[string]string   // path of each anchor
+	aliases map[string]string   // anchor of each alias node
+	merges  map[string][]string // anchors that are merged into each map
+	merged  map[string]string   // anchor that each merged key comes from
+	used    map[string]bool     // anchors that aliases or merge keys refer to
+}
+
+func newLayout() layout {
+	return layout{
+		keys:    make(map[string][]string),
+		anchors: make(map[string]string),
+		paths:   make(map[string]string),
+		aliases: make(map[string]string),
+		merges:  make(map[string][]string),
+		merged:  make(map[string]string),
+		used:    make(map[string]bool),
+	}
+}
+
+// layoutCodec is implemented by codecs that can report and
+// use the layout of documents
+type layoutCodec interface {
+	keepsLayout() bool
+	decodeLayouts(b []byte) ([]layout, error)
+	encodeLayouts(w *bytes.Buffer, docs []interface{}, layouts []layout) error
+}
+
+func (c YAMLCodec) keepsLayout() bool {
+	return c.Style.KeepKeyOrder || c.Style.KeepAnchors
+}
+
+// decodeLayouts returns the layout of each document of b
+func (YAMLCodec) decodeLayouts(b []byte) ([]layout, error) {
+	layouts := make([]layout, 0)
+
+	dec := yamlv3.NewDecoder(bytes.NewReader(b))
+	for {
+		var node yamlv3.Node
+		if err := dec.Decode(&node); err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return nil, wrapErr(err)
+		}
+
+		l := newLayout()
+		if len(node.Content) > 0 {
+			l.walk("", node.Content[0], false)
+		}
+		layouts = append(layouts, l)
+	}
+
+	return layouts, nil
+}
+
+// walk adds n and its children to the layout. Keys of merged maps are
+// added in the position of the merge key. Nodes that are reached
+// through an alias only add their key order
+func (l layout) walk(p string, n *yamlv3.Node, aliased bool) {
+	if n.Kind == yamlv3.AliasNode {
+		if !aliased {
+			l.aliases[p] = n.Value
+			l.used[n.Value] = true
+		}
+		l.walk(p, n.Alias, true)
+		return
+	}
+
+	if n.Anchor != "" && !aliased {
+		l.anchors[p] = n.Anchor
+		l.paths[n.Anchor] = p
+	}
+
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		keys := make([]string, 0, len(n.Content)/2)
+		own := make(map[string]bool, len(n.Content)/2)
+		merges := make([]*yamlv3.Node, 0)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.ShortTag() == "!!merge" {
+				keys = append(keys, mergedKeys(v)...)
+				merges = append(merges, v)
+				continue
+			}
+			keys = append(keys, k.Value)
+			own[k.Value] = true
+			l.walk(joinPath(p, k.Value), v, aliased)
+		}
+		l.keys[p] = keys
+
+		if !aliased {
+			for _, j := range merges {
+				l.merge(p, j, own)
+			}
+		}
+	case yamlv3.SequenceNode:
+		for i, j := range n.Content {
+			l.walk(joinPath(p, "["+strconv.Itoa(i)+"]"), j, aliased)
+		}
+	}
+}
+
+// merge records the anchors that the merge key value n of the map at p
+// refers to. Keys that the map does not define itself are recorded as
+// merged from the first anchor that has them
+func (l layout) merge(p string, n *yamlv3.Node, own map[string]bool) {
+	sources := []*yamlv3.Node{n}
+	if n.Kind == yamlv3.SequenceNode {
+		sources = n.Content
+	}
+
+	for _, j := range sources {
+		if j.Kind != yamlv3.AliasNode {
+			continue
+		}
+		l.merges[p] = append(l.merges[p], j.Value)
+		l.used[j.Value] = true
+
+		for _, k := range mergedKeys(j) {
+			kp := joinPath(p, k)
+			if _, exists := l.merged[kp]; !exists && !own[k] {
+				l.merged[kp] = j.Value
+			}
+		}
+	}
+}
+
+// mergedKeys returns the keys of the maps that a merge key refers to
+func mergedKeys(n *yamlv3.Node) []string {
+	if n.Kind == yamlv3.AliasNode {
+		n = n.Alias
+	}
+
+	keys := make([]string, 0)
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].ShortTag() == "!!merge" {
+				keys = append(keys, mergedKeys(n.Content[i+1])...)
+				continue
+			}
+			keys = append(keys, n.Content[i].Value)
+		}
+	case yamlv3.SequenceNode:
+		for _, j := range n.Content {
+			keys = append(keys, mergedKeys(j)...)
+		}
+	}
+	return keys
+}
+
+func joinPath(p, k string) string {
+	if p == "" {
+		return k
+	}
+	return p + "." + k
+}
+
+// encodeLayouts writes docs with the codec's style. layouts can be
+// nil or hold a layout for each document. Documents are built as
+// yaml.v3 nodes and written by the yaml.v3 encoder
+func (c YAMLCodec) encodeLayouts(w *bytes.Buffer, docs []interface{}, layouts []layout) error {
+	indent := c.Style.Indent
+	if indent < 2 {
+		indent = 2
+	}
+
+	for i, j := range docs {
+		b := &yamlBuilder{
+			style:   c.Style,
+			defined: make(map[string]interface{}),
+		}
+		if c.keepsLayout() && i < len(layouts) {
+			b.layout = layouts[i]
+		}
+
+		n, err := b.document(j)
+		if err != nil {
+			return wrapErr(err)
+		}
+
+		var out bytes.Buffer
+		enc := yamlv3.NewEncoder(&out)
+		enc.SetIndent(indent)
+		if err := enc.Encode(n); err != nil {
+			return wrapErr(err)
+		}
+		if err := enc.Close(); err != nil {
+			return wrapErr(err)
+		}
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if !c.Style.IndentSequences {
+			if lines, err = compactSequences(lines); err != nil {
+				return wrapErr(err)
+			}
+		}
+		if c.Style.LineWidth > 0 {
+			if lines, err = foldLines(lines, c.Style.LineWidth, indent); err != nil {
+				return wrapErr(err)
+			}
+		}
+
+		if c.Style.DocumentStart || i > 0 {
+			w.WriteString("---\n")
+		}
+		w.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+
+	return nil
+}
+
+// yamlBuilder builds the yaml.v3 node of a document. defined holds
+// the value of each anchor that has been added
+type yamlBuilder struct {
+	style   YAMLStyle
+	layout  layout
+	defined map[string]interface{}
+}
+
+func (b *yamlBuilder) document(v interface{}) (*yamlv3.Node, error) {
+	v, err := normalize(v)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	n, err := b.node(v, "")
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return &yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{n}}, nil
+}
+
+// node returns the node of the value v at path p
+func (b *yamlBuilder) node(v interface{}, p string) (*yamlv3.Node, error) {
+	if a := b.alias(p, v); a != "" {
+		return &yamlv3.Node{Kind: yamlv3.AliasNode, Value: a}, nil
+	}
+	a := b.anchor(p, v)
+
+	var n *yamlv3.Node
+	var err error
+	switch obj := v.(type) {
+	case map[interface{}]interface{}:
+		n, err = b.mapping(obj, p)
+	case []interface{}:
+		n, err = b.sequence(obj, p)
+	default:
+		n, err = b.scalar(v, false)
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	n.Anchor = a
+	return n, nil
+}
+
+func (b *yamlBuilder) mapping(m map[interface{}]interface{}, p string) (*yamlv3.Node, error) {
+	n := &yamlv3.Node{Kind: yamlv3.MappingNode}
+
+	keys, anchors := b.merge(m, p, b.keys(m, p))
+	if len(anchors) > 0 {
+		merge := &yamlv3.Node{Kind: yamlv3.SequenceNode, Style: yamlv3.FlowStyle}
+		for _, j := range anchors {
+			merge.Content = append(merge.Content, &yamlv3.Node{Kind: yamlv3.AliasNode, Value: j})
+		}
+		if len(anchors) == 1 {
+			merge = merge.Content[0]
+		}
+		n.Content = append(n.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: "<<"}, merge)
+	}
+
+	for _, k := range keys {
+		key, err := b.scalar(k, true)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+
+		v, err := b.node(m[k], joinPath(p, fmt.Sprint(k)))
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		n.Content = append(n.Content, key, v)
+	}
+
+	return n, nil
+}
+
+func (b *yamlBuilder) sequence(a []interface{}, p string) (*yamlv3.Node, error) {
+	n := &yamlv3.Node{Kind: yamlv3.SequenceNode}
+	for i, j := range a {
+		v, err := b.node(j, joinPath(p, "["+strconv.Itoa(i)+"]"))
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		n.Content = append(n.Content, v)
+	}
+	return n, nil
+}
+
+// keys returns the keys of m in the order they will be written
+func (b *yamlBuilder) keys(m map[interface{}]interface{}, p string) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	rest := make([]interface{}, 0, len(m))
+
+	seen := make(map[string]bool, len(m))
+	byName := make(map[string]interface{}, len(m))
+	for k := range m {
+		byName[fmt.Sprint(k)] = k
+	}
+
+	for _, j := range b.layout.keys[p] {
+		if k, exists := byName[j]; exists && !seen[j] {
+			seen[j] = true
+			keys = append(keys, k)
+		}
+	}
+
+	for k := range m {
+		if !seen[fmt.Sprint(k)] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return fmt.Sprint(rest[i]) < fmt.Sprint(rest[j])
+	})
+
+	return append(keys, rest...)
+}
+
+// scalar returns the node of a scalar value. Strings are quoted in the
+// configured style, except for keys which are quoted only when needed.
+// Strings that yaml.v2 would not read back as the same string are
+// always quoted
+func (b *yamlBuilder) scalar(v interface{}, key bool) (*yamlv3.Node, error) {
+	n := &yamlv3.Node{Kind: yamlv3.ScalarNode}
+
+	switch obj := v.(type) {
+	case nil:
+		n.Tag, n.Value = "!!null", "null"
+	case float64:
+		n.Tag, n.Value = "!!float", formatFloat(obj)
+	case string:
+		n.Tag, n.Value = "!!str", obj
+		switch {
+		case strings.Contains(obj, "\n") && !key:
+			n.Style = yamlv3.LiteralStyle
+		case key && plainSafe(obj):
+		case key:
+			n.Style = yamlv3.DoubleQuotedStyle
+		case b.style.Quote == QuoteSingle && printable(obj):
+			n.Style = yamlv3.SingleQuotedStyle
+		case b.style.Quote == QuoteAuto && plainSafe(obj):
+		default:
+			n.Style = yamlv3.DoubleQuotedStyle
+		}
+	default:
+		if err := n.Encode(v); err != nil {
+			return nil, wrapErr(err)
+		}
+	}
+
+	return n, nil
+}
+
+// compactSequences moves block sequences that are map values to the
+// indentation of their key (key:\n- item), as yaml.v3 always indents
+// them. The lines of each sequence are found from the node positions
+// of the encoded document and are moved as a whole, so nested values
+// and block scalars keep their relative indentation
+func compactSequences(lines []string) ([]string, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	shift := make([]int, len(lines))
+	var walk func(n *yamlv3.Node)
+	walk = func(n *yamlv3.Node) {
+		for i, j := range n.Content {
+			walk(j)
+
+			if n.Kind != yamlv3.MappingNode || i%2 == 0 {
+				continue
+			}
+			if j.Kind != yamlv3.SequenceNode || j.Style&yamlv3.FlowStyle != 0 || len(j.Content) == 0 {
+				continue
+			}
+
+			start := j.Content[0].Line - 1
+			if start >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[start]), "-") {
+				continue
+			}
+			key := n.Content[i-1].Column - 1
+			d := indentOf(lines[start]) - key
+			for l := start; l < len(lines) && d > 0; l++ {
+				if strings.TrimSpace(lines[l]) != "" && indentOf(lines[l]) <= key {
+					break
+				}
+				shift[l] += d
+			}
+		}
+	}
+	walk(&doc)
+
+	for i, j := range lines {
+		d := shift[i]
+		if d > indentOf(j) {
+			d = indentOf(j)
+		}
+		lines[i] = j[d:]
+	}
+
+	return lines, nil
+}
+
+// foldLines folds plain and quoted scalars that are map values or
+// sequence items so that lines do not exceed the width w. Continuation
+// lines are indented by indent spaces from their key or start after
+// the sequence's -
+func foldLines(lines []string, w, indent int) ([]string, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	fold := func(n *yamlv3.Node, i int) {
+		if n.Kind != yamlv3.ScalarNode || n.Anchor != "" || n.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle|yamlv3.TaggedStyle) != 0 {
+			return
+		}
+		l, col := n.Line-1, n.Column-1
+		if l >= len(lines) || strings.Contains(lines[l], "\n") || col >= len(lines[l]) {
+			return
+		}
+		lines[l] = lines[l][:col] + foldString(lines[l][col:], col, i, w)
+	}
+
+	var walk func(n *yamlv3.Node)
+	walk = func(n *yamlv3.Node) {
+		if n.Style&yamlv3.FlowStyle != 0 {
+			return
+		}
+		for i, j := range n.Content {
+			switch {
+			case n.Kind == yamlv3.MappingNode && i%2 == 1:
+				fold(j, n.Content[i-1].Column-1+indent)
+			case n.Kind == yamlv3.SequenceNode:
+				fold(j, j.Column-1)
+			}
+			walk(j)
+		}
+	}
+	walk(&doc)
+
+	return strings.Split(strings.Join(lines, "\n"), "\n"), nil
+}
+
+// foldString breaks s on single spaces so that lines do not exceed
+// the width w. col is the column that s starts at and continuation
+// lines are indented by i spaces
+func foldString(s string, col, i, w int) string {
+	if col+len(s) <= w {
+		return s
+	}
+
+	var b strings.Builder
+	start, last := 0, -1
+	for j := 2; j < len(s)-1; j++ {
+		if !foldable(s, j) {
+			continue
+		}
+		if col+j-start > w && last > start {
+			b.WriteString(s[start:last] + "\n" + strings.Repeat(" ", i))
+			start, col = last+1, i
+		}
+		last = j
+	}
+	if col+len(s)-start > w && last > start {
+		b.WriteString(s[start:last] + "\n" + strings.Repeat(" ", i))
+		start = last + 1
+	}
+	b.WriteString(s[start:])
+
+	return b.String()
+}
+
+// foldable reports if s can be broken on its j'th byte. Breaks are
+// done on single spaces that are not followed by an indicator
+func foldable(s string, j int) bool {
+	if s[j] != ' ' || s[j-1] == ' ' || s[j+1] == ' ' {
+		return false
+	}
+	return strings.IndexByte("-?:#,[]{}&*!|>'\"%@`", s[j+1]) < 0
+}
+
+// indentOf returns the number of leading spaces of s
+func indentOf(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// normalize converts objects that are not produced by the yaml
+// decoder (e.g. structs or map[string]interface{}) to decoded types
+func normalize(v interface{}) (interface{}, error) {
+	switch obj := v.(type) {
+	case nil, string, bool, int, int64, uint64, float64, time.Time:
+		return v, nil
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(obj))
+		for k, j := range obj {
+			n, err := normalize(j)
+			if err != nil {
+				return nil, wrapErr(err)
+			}
+			m[k] = n
+		}
+		return m, nil
+	case []interface{}:
+		a := make([]interface{}, len(obj))
+		for i, j := range obj {
+			n, err := normalize(j)
+			if err != nil {
+				return nil, wrapErr(err)
+			}
+			a[i] = n
+		}
+		return a, nil
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Float32:
+		// Scalars are written through yaml.Marshal
+		return v, nil
+	}
+
+	var data interface{}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if err := yaml.Unmarshal(b, &data); err != nil {
+		return nil, wrapErr(err)
+	}
+	return data, nil
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEn") {
+		s += ".0"
+	}
+	return s
+}
+
+// printable reports if all runes of s are printable
+func printable(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// plainSafe reports if s can be written as a plain scalar and
+// read back as the same string
+func plainSafe(s string) bool {
+	if s == "" || !printable(s) || s != strings.TrimSpace(s) {
+		return false
+	}
+	if strings.IndexByte("?:,[]{}#&*!|>'\"%@`", s[0]) >= 0 {
+		return false
+	}
+	if s[0] == '-' && (len(s) == 1 || s[1] == ' ') {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+
+	var data interface{}
+	if err := yaml.Unmarshal([]byte(s), &data); err != nil {
+		return false
+	}
+	return data == s
+}
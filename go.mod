@@ -1,9 +1,12 @@
 module github.com/ulfox/dby
 
-go 1.13
+go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/likexian/gokit v0.25.2
 	github.com/pkg/errors v0.9.1
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+const anchoredDoc = `defaults: &defaults
+  adapter: postgres
+  pool: 5
+hosts: &hosts
+- a
+- b
+development:
+  <<: *defaults
+  database: dev
+test:
+  <<: *defaults
+  pool: 10
+  hosts: *hosts
+`
+
+// TestKeepAnchors run unit tests on keeping anchors, aliases
+// and merge keys
+func TestKeepAnchors(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-anchors.yaml"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte(anchoredDoc), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path, db.YAMLCodec{Style: db.YAMLStyle{
+		KeepAnchors: true,
+	}})
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("test.adapter")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "postgres")
+
+	err = storage.Upsert("production.database", "prod")
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), anchoredDoc+`production:
+  database: prod
+`)
+
+	err = storage.SetAliasPolicy(db.AliasError).Upsert("test.hosts.[0]", "c")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("development.adapter", "mysql")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetAliasPolicy(db.AliasIgnore).Upsert("development.adapter", "mysql")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("hosts.[1]", "c")
+	assert.Equal(t, err, nil)
+
+	f, err = ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `defaults: &defaults
+  adapter: postgres
+  pool: 5
+hosts: &hosts
+- a
+- c
+development:
+  <<: *defaults
+  adapter: mysql
+  database: dev
+test:
+  <<: *defaults
+  pool: 10
+  hosts:
+  - a
+  - b
+production:
+  database: prod
+`)
+
+	state, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	val, err = state.GetPath("test.hosts.[1]")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "b")
+
+	val, err = state.GetPath("development.adapter")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "mysql")
+
+	path = ".test/db-anchored-items.yaml"
+	defer os.Remove(path)
+	items := `items:
+- &first
+  name: a
+  tags: &tags
+  - x
+- &list
+  - 1
+- *first
+- *list
+- &s str
+- *s
+other:
+  tags: *tags
+`
+	err = ioutil.WriteFile(path, []byte(items), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err = db.NewStorageFactory(path, db.YAMLCodec{Style: db.YAMLStyle{
+		KeepAnchors: true,
+	}})
+	assert.Equal(t, err, nil)
+
+	err = storage.Write()
+	assert.Equal(t, err, nil)
+
+	f, err = ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), items)
+}
+
+// TestResolvePath run unit tests on resolving paths through
+// aliases and merge keys
+func TestResolvePath(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(anchoredDoc + `nested:
+  <<: *defaults
+  base: &base
+    <<: *defaults
+    pool: 1
+  child:
+    <<: *base
+`))
+	assert.Equal(t, err, nil)
+
+	for k, v := range map[string]string{
+		"development.adapter":  "defaults.adapter",
+		"development.database": "development.database",
+		"test.pool":            "test.pool",
+		"test.hosts.[1]":       "hosts.[1]",
+		"nested.child.pool":    "nested.base.pool",
+		"nested.child.adapter": "defaults.adapter",
+	} {
+		p, err := storage.ResolvePath(k)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, p, v)
+	}
+
+	_, err = storage.ResolvePath("development.missing")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetAliasPolicy(db.AliasError).Upsert("defaults.pool", 1)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("development.database", "other")
+	assert.Equal(t, err, nil)
+
+	err = storage.UpsertGlobal("test.adapter", "other")
+	assert.NotEqual(t, err, nil)
+
+	val, err := storage.GetPath("test.adapter")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "postgres")
+}
+
+// TestExpandedAnchors run unit tests on dropping the anchors of
+// documents that are written without them
+func TestExpandedAnchors(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-expanded-anchors.yaml"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte("defaults: &d {a: 1}\ndev: *d\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	p, err := storage.ResolvePath("dev.a")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p, "defaults.a")
+
+	err = storage.Upsert("other", 1)
+	assert.Equal(t, err, nil)
+
+	p, err = storage.ResolvePath("dev.a")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, p, "dev.a")
+
+	err = storage.SetAliasPolicy(db.AliasError).Upsert("dev.a", 5)
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("defaults.a")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+}
@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestTOMLCodec run unit tests on toml storage files
+func TestTOMLCodec(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-config.toml"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte(`title = "app"
+
+[server]
+port = 8080
+hosts = ["a", "b"]
+
+[[users]]
+name = "x"
+
+[[users]]
+name = "y"
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("server.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 8080)
+
+	val, err = storage.GetPath("users.[1].name")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "y")
+
+	err = storage.Upsert("server.port", 9090)
+	assert.Equal(t, err, nil)
+
+	state, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	val, err = state.GetPath("server.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 9090)
+
+	val, err = state.GetPath("server.hosts.[0]")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "a")
+
+	err = storage.AddDoc()
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 1)
+
+	val, err = storage.GetPath("server.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 9090)
+}
+
+// TestPropertiesCodec run unit tests on the properties codec
+func TestPropertiesCodec(t *testing.T) {
+	t.Parallel()
+
+	c := db.PropertiesCodec{}
+	docs, err := c.Decode(strings.NewReader(`# comment
+! comment
+app.name = my app
+app.port: 8080
+app.hosts.[0]=a
+app.hosts.[1]=b
+key\ with\ spaces=value \
+    continued
+empty
+`))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, docs, []interface{}{
+		map[interface{}]interface{}{
+			"app": map[interface{}]interface{}{
+				"name":  "my app",
+				"port":  "8080",
+				"hosts": []interface{}{"a", "b"},
+			},
+			"key with spaces": "value continued",
+			"empty":           "",
+		},
+	})
+
+	var buf bytes.Buffer
+	err = c.Encode(&buf, docs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `app.hosts.[0]=a
+app.hosts.[1]=b
+app.name=my app
+app.port=8080
+empty=
+key\ with\ spaces=value continued
+`)
+
+	err = c.Encode(&buf, append(docs, docs[0]))
+	assert.NotEqual(t, err, nil)
+}
+
+// TestEnvCodec run unit tests on the env codec
+func TestEnvCodec(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-test.env"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte(`# comment
+export APP_NAME="my app"
+APP_PORT=8080 # inline comment
+APP_RAW='a "raw" value'
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetData(), map[interface{}]interface{}{
+		"APP_NAME": "my app",
+		"APP_PORT": "8080",
+		"APP_RAW":  `a "raw" value`,
+	})
+
+	err = storage.Upsert("APP_DEBUG", true)
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `APP_DEBUG=true
+APP_NAME="my app"
+APP_PORT=8080
+APP_RAW="a \"raw\" value"
+`)
+
+	err = storage.Upsert("APP.NESTED", "value")
+	assert.NotEqual(t, err, nil)
+
+	_, err = db.EnvCodec{}.Decode(strings.NewReader("NOT A LINE\n"))
+	assert.NotEqual(t, err, nil)
+
+	_, err = db.EnvCodec{}.Decode(strings.NewReader("KEY=\"unterminated\n"))
+	assert.NotEqual(t, err, nil)
+}
+
+type upperCodec struct {
+	db.YAMLCodec
+}
+
+func (c upperCodec) Encode(w io.Writer, docs []interface{}) error {
+	var buf bytes.Buffer
+	if err := c.YAMLCodec.Encode(&buf, docs); err != nil {
+		return err
+	}
+	_, err := w.Write(bytes.ToUpper(buf.Bytes()))
+	return err
+}
+
+// TestCodecRegistry run unit tests on registering codecs
+func TestCodecRegistry(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, db.CodecFor("a/b.JSON"), db.JSONCodec{})
+	assert.Equal(t, db.CodecFor("a/b.jsonl"), db.JSONCodec{NDJSON: true})
+	assert.Equal(t, db.CodecFor(".env"), db.EnvCodec{})
+	assert.Equal(t, db.CodecFor("a/b"), db.YAMLCodec{})
+
+	db.RegisterCodec(".upper", upperCodec{})
+	assert.Equal(t, db.CodecFor("b.upper"), upperCodec{})
+
+	path := ".test/db-registry.upper"
+	defer os.Remove(path)
+	os.Remove(path)
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("key", "value")
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), "KEY: VALUE\n")
+
+	path = ".test/db-explicit.conf"
+	defer os.Remove(path)
+	os.Remove(path)
+	storage, err = db.NewStorageFactory(path, db.JSONCodec{NDJSON: true})
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("key", "value")
+	assert.Equal(t, err, nil)
+
+	f, err = ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), "{\"key\":\"value\"}\n")
+
+	var buf bytes.Buffer
+	err = storage.ExportTo(&buf, db.ExportOpts{Codec: db.PropertiesCodec{}})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "key=value\n")
+}
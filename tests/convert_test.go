@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestConvert run unit tests on AssertData conversions
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert(
+		"spec",
+		map[string]interface{}{
+			"replicas": 3,
+			"ratio":    0.5,
+			"enabled":  true,
+			"timeout":  "1m30s",
+			"created":  "2021-06-01T10:00:00Z",
+			"day":      "2021-06-01",
+			"ports":    []int{80, 443},
+			"labels":   map[string]string{"app": "web"},
+			"containers": []map[string]interface{}{
+				{"name": "web", "ports": []int{80}},
+			},
+		},
+	)
+	assert.Equal(t, err, nil)
+
+	assertData := db.NewConvertFactory()
+
+	i64, err := assertData.Input(storage.GetData()).Key("spec").Key("replicas").GetInt64()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, i64, int64(3))
+
+	f, err := assertData.Input(storage.GetData()).Key("spec").Key("replicas").GetFloat()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, f, float64(3))
+
+	f, err = assertData.Input(storage.GetData()).Key("spec").Key("ratio").GetFloat()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, f, 0.5)
+
+	b, err := assertData.Input(storage.GetData()).Key("spec").Key("enabled").GetBool()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, b, true)
+
+	d, err := assertData.Input(storage.GetData()).Key("spec").Key("timeout").GetDuration()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, d, 90*time.Second)
+
+	tm, err := assertData.Input(storage.GetData()).Key("spec").Key("created").GetTime()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tm.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)), true)
+
+	tm, err = assertData.Input(storage.GetData()).Key("spec").Key("day").GetTime()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, tm.Equal(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)), true)
+
+	m, err := assertData.Input(storage.GetData()).Key("spec").GetMapInterface()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m["labels"], map[string]interface{}{"app": "web"})
+
+	am, err := assertData.Input(storage.GetData()).Key("spec").Key("containers").GetArrayMap()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, am[0]["name"], "web")
+	assert.Equal(t, am[0]["ports"], []interface{}{80})
+
+	ai, err := assertData.Input(storage.GetData()).Key("spec").Key("ports").GetArrayInt()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ai, []int{80, 443})
+
+	_, err = assertData.Input(storage.GetData()).Key("spec").Key("labels").GetArrayInt()
+	assert.NotEqual(t, err, nil)
+
+	_, err = assertData.Input(storage.GetData()).Key("spec").Key("enabled").GetDuration()
+	assert.NotEqual(t, err, nil)
+
+	assertData.Input(storage.GetData()).Key("spec").Key("missing")
+	assert.NotEqual(t, assertData.GetError(), nil)
+
+	assertData.Input(storage.GetData()).Key("spec").Key("ports").Index(2)
+	assert.NotEqual(t, assertData.GetError(), nil)
+
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("missing").GetIntOrDefault(1), 1)
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("replicas").GetIntOrDefault(1), 3)
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("enabled").GetStringOrDefault("no"), "no")
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("ratio").GetBoolOrDefault(true), true)
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("ratio").GetFloatOrDefault(1), 0.5)
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("ratio").GetInt64OrDefault(1), int64(1))
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("missing").GetDurationOrDefault(time.Second), time.Second)
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("missing").GetArrayIntOrDefault([]int{1}), []int{1})
+	assert.Equal(t, assertData.Input(storage.GetData()).Key("spec").Key("labels").GetMapOrDefault(nil), map[string]string{"app": "web"})
+}
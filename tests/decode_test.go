@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+type testContainer struct {
+	Name  string            `yaml:"name"`
+	Image string            `yaml:"image"`
+	Ports []int             `yaml:"ports,omitempty"`
+	Env   map[string]string `yaml:"env,omitempty"`
+}
+
+type testSpec struct {
+	Replicas   int             `yaml:"replicas"`
+	Containers []testContainer `yaml:"containers"`
+}
+
+// TestDecode run unit tests on decoding paths into structs
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	spec := testSpec{
+		Replicas: 2,
+		Containers: []testContainer{
+			{
+				Name:  "web",
+				Image: "nginx:1.21",
+				Ports: []int{80, 443},
+				Env:   map[string]string{"MODE": "prod"},
+			},
+		},
+	}
+
+	err = storage.UpsertStruct("spec", spec)
+	assert.Equal(t, err, nil)
+
+	err = storage.UpsertStruct("spec-ptr", &spec)
+	assert.Equal(t, err, nil)
+
+	err = storage.UpsertStruct("spec-map", map[string]string{})
+	assert.NotEqual(t, err, nil)
+
+	val, err := storage.GetPath("spec.containers.[0].image")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "nginx:1.21")
+
+	var out testSpec
+	err = storage.GetPathInto("spec", &out)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, out, spec)
+
+	var container testContainer
+	err = storage.GetPathInto("spec-ptr.containers.[0]", &container)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, container, spec.Containers[0])
+
+	err = storage.GetPathInto("spec.missing", &container)
+	assert.NotEqual(t, err, nil)
+
+	var replicas int
+	assertData := db.NewConvertFactory()
+	err = assertData.Input(storage.GetData()).Key("spec").Key("replicas").Decode(&replicas)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replicas, 2)
+
+	err = assertData.Input(storage.GetData()).Key("spec").Decode(&replicas)
+	assert.NotEqual(t, err, nil)
+}
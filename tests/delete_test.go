@@ -2,6 +2,7 @@ package tests
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/likexian/gokit/assert"
@@ -56,3 +57,26 @@ func TestDelete(t *testing.T) {
 	err = os.Remove(path)
 	assert.Equal(t, err, nil)
 }
+
+// TestDeleteRootIndex run unit tests for deleting items
+// of documents that are lists
+func TestDeleteRootIndex(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader("[a, b, c]\n---\n[d, e]\n"))
+	assert.Equal(t, err, nil)
+
+	err = storage.Delete("[0]")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetData(), []interface{}{"c", "b"})
+
+	err = storage.DeleteGlobal("[1]")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAllData(), []interface{}{
+		[]interface{}{"c"},
+		[]interface{}{"d"},
+	})
+}
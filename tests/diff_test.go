@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestDiff run unit tests on diffing values
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	a := map[interface{}]interface{}{
+		"kind": "Service",
+		"spec": map[interface{}]interface{}{
+			"ports": []interface{}{80, 443},
+			"type":  "ClusterIP",
+		},
+		"status": "ok",
+	}
+	b := map[string]interface{}{
+		"kind": "Service",
+		"spec": map[string]interface{}{
+			"ports":    []interface{}{8080},
+			"selector": map[string]interface{}{"app": "web"},
+			"type":     "ClusterIP",
+		},
+		"status": []interface{}{"ok"},
+	}
+
+	assert.Equal(t, db.Diff(a, b), []db.Change{
+		{Path: "spec.ports.[0]", Op: db.OpChanged, Old: 80, New: 8080},
+		{Path: "spec.ports.[1]", Op: db.OpRemoved, Old: 443},
+		{Path: "spec.selector", Op: db.OpAdded, New: map[interface{}]interface{}{"app": "web"}},
+		{Path: "status", Op: db.OpChanged, Old: "ok", New: []interface{}{"ok"}},
+	})
+
+	assert.Equal(t, db.Diff(a, a), []db.Change{})
+	assert.Equal(t, db.Diff(1, "1"), []db.Change{{Op: db.OpChanged, Old: 1, New: "1"}})
+}
+
+// TestDiffStorage run unit tests on diffing documents and files
+func TestDiffStorage(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-diff.yaml"
+	os.Remove(path)
+	defer os.Remove(path)
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(`kind: Deployment
+spec:
+  replicas: 1
+---
+kind: Deployment
+spec:
+  replicas: 2
+`))
+	assert.Equal(t, err, nil)
+
+	changes, err := storage.DiffDocs(0, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, changes, []db.Change{
+		{Path: "spec.replicas", Op: db.OpChanged, Old: 1, New: 2},
+	})
+
+	_, err = storage.DiffDocs(0, 2)
+	assert.NotEqual(t, err, nil)
+
+	live := ".test/db-diff-live.json"
+	defer os.Remove(live)
+	err = ioutil.WriteFile(live, []byte(`{"kind": "Deployment", "spec": {"replicas": 3}}`), 0600)
+	assert.Equal(t, err, nil)
+
+	changes, err = storage.DiffFile(live)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, changes, []db.Change{
+		{Path: "[0].spec.replicas", Op: db.OpChanged, Old: 1, New: 3},
+		{Path: "[1]", Op: db.OpRemoved, Old: map[interface{}]interface{}{
+			"kind": "Deployment",
+			"spec": map[interface{}]interface{}{"replicas": 2},
+		}},
+	})
+
+	_, err = storage.DiffFile(".test/missing.yaml")
+	assert.NotEqual(t, err, nil)
+
+	before, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+
+	changes, err = storage.DryRun(func(s *db.Storage) error {
+		if err := s.UpsertGlobal("spec.replicas", 5); err != nil {
+			return err
+		}
+		return s.AddDoc()
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, changes, []db.Change{
+		{Path: "[0].spec.replicas", Op: db.OpChanged, Old: 1, New: 5},
+		{Path: "[1].spec.replicas", Op: db.OpChanged, Old: 2, New: 5},
+		{Path: "[2]", Op: db.OpAdded, New: map[interface{}]interface{}{}},
+	})
+
+	after, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(after), string(before))
+
+	val, err := storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+
+	_, err = storage.DryRun(func(s *db.Storage) error {
+		if err := s.Upsert("spec.replicas", 7); err != nil {
+			return err
+		}
+		return s.Write()
+	})
+	assert.NotEqual(t, err, nil)
+
+	after, err = ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(after), string(before))
+
+	_, err = storage.DryRun(func(s *db.Storage) error {
+		return s.Switch(5)
+	})
+	assert.NotEqual(t, err, nil)
+}
+
+// TestDryRunSchema run unit tests on changing schemas in a dry run
+func TestDryRunSchema(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("a", 1)
+	assert.Equal(t, err, nil)
+
+	err = storage.SetSchema(db.AllDocs, []byte(`{"properties": {"a": {"type": "integer"}}}`))
+	assert.Equal(t, err, nil)
+
+	err = storage.SetSchema("app=web", []byte(`{"required": ["b"]}`))
+	assert.Equal(t, err, nil)
+
+	changes, err := storage.DryRun(func(s *db.Storage) error {
+		s.UnsetSchema(db.AllDocs)
+		return s.Upsert("a", "x")
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, changes, []db.Change{
+		{Path: "[0].a", Op: db.OpChanged, Old: 1, New: "x"},
+	})
+
+	err = storage.Upsert("a", "x")
+	assert.NotEqual(t, err, nil)
+}
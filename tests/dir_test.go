@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestImportExportDir run unit tests on directory import and export
+func TestImportExportDir(t *testing.T) {
+	t.Parallel()
+
+	dir := ".test/import-dir"
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.yaml":          "kind: Deployment\nname: a\n---\nkind: Service\nname: a\n",
+		"b.yml":           "kind: Deployment\nname: b\n",
+		"notes.txt":       "not: imported\n",
+		"nested/c.yaml":   "kind: Deployment\nname: c\n",
+		"nested/d/d.yaml": "kind: Service\nname: d\n",
+	}
+	for k, v := range files {
+		err := os.MkdirAll(filepath.Dir(filepath.Join(dir, k)), 0700)
+		assert.Equal(t, err, nil)
+		err = ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0600)
+		assert.Equal(t, err, nil)
+	}
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportDir(dir, "*.yaml", false)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	origin, exists := storage.DocOrigin(1)
+	assert.Equal(t, exists, true)
+	assert.Equal(t, origin, "a.yaml")
+
+	err = storage.DeleteAll(true).ImportDir(dir, "*.y*ml", true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 5)
+
+	var origins []string
+	for i := range storage.GetAllData() {
+		origin, exists := storage.DocOrigin(i)
+		assert.Equal(t, exists, true)
+		origins = append(origins, filepath.ToSlash(origin))
+	}
+	assert.Equal(t, origins, []string{
+		"a.yaml", "a.yaml", "b.yml", "nested/c.yaml", "nested/d/d.yaml",
+	})
+
+	err = storage.DeleteAll(true).ImportDir(dir, "nested/*.yaml", true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 1)
+
+	err = storage.DeleteAll(true).ImportDir(dir, "*.y*ml", true)
+	assert.Equal(t, err, nil)
+
+	err = storage.ImportDir(dir, "[", true)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.ImportDir(dir+"/missing", "*", true)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 5)
+
+	err = storage.Switch(2)
+	assert.Equal(t, err, nil)
+	err = storage.Upsert("replicas", 3)
+	assert.Equal(t, err, nil)
+
+	err = storage.AddDoc()
+	assert.Equal(t, err, nil)
+	err = storage.Upsert("kind", "ConfigMap")
+	assert.Equal(t, err, nil)
+
+	out := ".test/export-dir"
+	os.RemoveAll(out)
+	defer os.RemoveAll(out)
+
+	err = storage.ExportDir(out)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetName("../../escape", 5)
+	assert.Equal(t, err, nil)
+
+	err = storage.ExportDir(out)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, fileExists(".test/escape.yaml"), false)
+	assert.Equal(t, fileExists(out), false)
+
+	err = storage.UnnameDoc("../../escape")
+	assert.Equal(t, err, nil)
+	err = storage.SetName("configmap/e", 5)
+	assert.Equal(t, err, nil)
+
+	err = storage.ExportDir(out)
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(filepath.Join(out, "a.yaml"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), files["a.yaml"])
+
+	f, err = ioutil.ReadFile(filepath.Join(out, "b.yml"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), "kind: Deployment\nname: b\nreplicas: 3\n")
+
+	f, err = ioutil.ReadFile(filepath.Join(out, "nested", "d", "d.yaml"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), files["nested/d/d.yaml"])
+
+	f, err = ioutil.ReadFile(filepath.Join(out, "configmap", "e.yaml"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), "kind: ConfigMap\n")
+
+	err = storage.DeleteDoc(0)
+	assert.Equal(t, err, nil)
+
+	origin, exists = storage.DocOrigin(0)
+	assert.Equal(t, exists, true)
+	assert.Equal(t, origin, "a.yaml")
+
+	err = storage.DeleteDoc(0)
+	assert.Equal(t, err, nil)
+
+	origin, exists = storage.DocOrigin(0)
+	assert.Equal(t, exists, true)
+	assert.Equal(t, origin, "b.yml")
+}
@@ -0,0 +1,286 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestDocNames run unit tests on document names after
+// documents are deleted or imported
+func TestDocNames(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-doc-names.yaml"
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNames("kind", "metadata.name")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.ListDocs()), 8)
+
+	err = storage.SetName("Listener", 1)
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("listener")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 1)
+
+	err = storage.DeleteDoc(1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.ListDocs()), 7)
+
+	err = storage.SwitchDoc("listener")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.DeleteDoc(0)
+	assert.Equal(t, err, nil)
+
+	err = storage.ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 14)
+
+	for name, i := range storage.Lib() {
+		err = storage.SwitchDoc(name)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, storage.GetAD(), i)
+
+		kind, err := storage.GetPath("kind")
+		assert.Equal(t, err, nil)
+		docName, err := storage.GetPath("metadata.name")
+		assert.Equal(t, err, nil)
+
+		doc, exists := storage.LibIndex(kind.(string) + "/" + docName.(string))
+		assert.Equal(t, exists, true)
+		assert.Equal(t, doc, i)
+	}
+
+	err = storage.SwitchDoc("service/caller-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 4)
+
+	err = storage.DeleteDoc(2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 3)
+
+	val, err := storage.GetPath("kind")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "Service")
+
+	err = os.Remove(path)
+	assert.Equal(t, err, nil)
+}
+
+// TestDocManagement run unit tests on renaming, moving
+// and inserting documents
+func TestDocManagement(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNames("kind", "metadata.name")
+	assert.Equal(t, err, nil)
+
+	docs := storage.ListDocs()
+	assert.Equal(t, len(docs), 8)
+	for i, j := range docs {
+		assert.Equal(t, j.Index, i)
+	}
+	assert.Equal(t, docs[0], db.DocRef{Name: "horizontalpodautoscaler/listener-svc", Index: 0})
+	assert.Equal(t, docs[7], db.DocRef{Name: "poddisruptionbudget/caller-svc", Index: 7})
+
+	name, ok := storage.DocName(1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, name, "deployment/listener-svc")
+
+	_, ok = storage.DocName(8)
+	assert.Equal(t, ok, false)
+
+	err = storage.RenameDoc("Deployment/listener-svc", "listener")
+	assert.Equal(t, err, nil)
+
+	name, ok = storage.DocName(1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, name, "listener")
+
+	err = storage.RenameDoc("deployment/listener-svc", "other")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.RenameDoc("listener", "service/listener-svc")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.UnnameDoc("listener")
+	assert.Equal(t, err, nil)
+
+	_, ok = storage.DocName(1)
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(storage.ListDocs()), 7)
+
+	err = storage.UnnameDoc("listener")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.DeleteDocByName("service/listener-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 7)
+
+	err = storage.DeleteDocByName("service/listener-svc")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SwitchDoc("service/caller-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 5)
+
+	err = storage.MoveDoc(5, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 0)
+
+	err = storage.MoveDoc(1, 6)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 0)
+
+	err = storage.MoveDoc(1, 7)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.InsertDoc(2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 2)
+	assert.Equal(t, len(storage.GetAllData()), 8)
+
+	err = storage.InsertDoc(8)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 8)
+
+	err = storage.InsertDoc(10)
+	assert.NotEqual(t, err, nil)
+
+	for _, j := range storage.ListDocs() {
+		err = storage.Switch(j.Index)
+		assert.Equal(t, err, nil)
+
+		kind, err := storage.GetPath("kind")
+		assert.Equal(t, err, nil)
+		docName, err := storage.GetPath("metadata.name")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, j.Name, strings.ToLower(kind.(string))+"/"+docName.(string))
+	}
+
+	name, ok = storage.DocName(0)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, name, "service/caller-svc")
+
+	name, ok = storage.DocName(7)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, name, "horizontalpodautoscaler/listener-svc")
+
+	_, ok = storage.DocName(2)
+	assert.Equal(t, ok, false)
+}
+
+// TestSetNamesTemplate run unit tests on naming documents
+// with a template
+func TestSetNamesTemplate(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNamesTemplate("kind-name")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.metadata..name}}")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.metadata.name | lower}}", db.CollisionSuffix)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(storage.ListDocs()), 0)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.metadata.namespace}}/{{.metadata.name}}")
+	assert.Equal(t, err, nil)
+
+	i, ok := storage.LibIndex("deployment/echoserver/listener-svc")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 1)
+
+	err = storage.SetNamesTemplate("{{ .metadata.namespace }}")
+	assert.NotEqual(t, err, nil)
+	_, ok = storage.LibIndex("echoserver")
+	assert.Equal(t, ok, false)
+
+	err = storage.SetNamesTemplate("{{.metadata.namespace}}", db.CollisionSkip)
+	assert.Equal(t, err, nil)
+	i, ok = storage.LibIndex("echoserver")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 0)
+	i, ok = storage.LibIndex("sysdebug")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 4)
+	assert.Equal(t, len(storage.ListDocs()), 10)
+
+	err = storage.SetNamesTemplate("ns-{{.metadata.namespace}}", db.CollisionSuffix)
+	assert.Equal(t, err, nil)
+	for j := 0; j < 8; j++ {
+		name := "ns-echoserver"
+		if j > 3 {
+			name = "ns-sysdebug"
+		}
+		if j%4 > 0 {
+			name = fmt.Sprintf("%s-%d", name, j%4)
+		}
+		i, ok = storage.LibIndex(name)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, i, j)
+	}
+
+	// Both services have the same port name
+	err = storage.SetNamesTemplate("{{.kind}}/{{.spec.ports.[0].name}}")
+	assert.NotEqual(t, err, nil)
+	_, ok = storage.LibIndex("service/tcp-web")
+	assert.Equal(t, ok, false)
+
+	// Only documents that have all the paths get a name
+	err = storage.SetNamesTemplate("{{.kind}}/{{.spec.ports.[0].name}}", db.CollisionSuffix)
+	assert.Equal(t, err, nil)
+	i, ok = storage.LibIndex("service/tcp-web")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 2)
+
+	i, ok = storage.LibIndex("service/tcp-web-1")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 6)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.spec.ports.[0].name}}", db.CollisionOverwrite)
+	assert.Equal(t, err, nil)
+	i, ok = storage.LibIndex("service/tcp-web")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 6)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.spec.minReplicas}}")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetNames("kind", "spec.replicas")
+	assert.NotEqual(t, err, nil)
+
+	// SetNames points a name that is generated twice to the last document
+	err = storage.SetNames("kind", "spec.ports.[0].name")
+	assert.Equal(t, err, nil)
+	i, ok = storage.LibIndex("service/tcp-web")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 6)
+}
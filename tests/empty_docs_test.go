@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+const mixedDocs = `---
+---
+kind: Service
+---
+- name: a
+  value: 1
+- name: b
+---
+some-value
+---
+{}
+`
+
+// TestImportDocTypes run unit tests on importing non map documents
+func TestImportDocTypes(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(mixedDocs))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 3)
+
+	err = storage.Switch(1)
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("[0].name")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "a")
+
+	keys, err := storage.FindKeys("value")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, keys, []string{"[0].value"})
+
+	err = storage.Upsert("[1].value", 2)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("[0].name", "c")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("[2].name", "d")
+	assert.NotEqual(t, err, nil)
+
+	assert.Equal(t, storage.GetData(), []interface{}{
+		map[interface{}]interface{}{"name": "c", "value": 1},
+		map[interface{}]interface{}{"name": "b", "value": 2},
+	})
+
+	err = storage.Switch(2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetData(), "some-value")
+
+	_, err = storage.GetPath("kind")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("kind", "Service")
+	assert.NotEqual(t, err, nil)
+}
+
+// TestKeepEmptyDocs run unit tests on keeping empty documents
+func TestKeepEmptyDocs(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		KeepEmptyDocs(true).
+		ImportFrom(strings.NewReader(mixedDocs))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 5)
+	assert.Equal(t, storage.GetData(), nil)
+
+	var buf bytes.Buffer
+	err = storage.ExportTo(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "null\n---\nkind: Service\n---\n- name: a\n  value: 1\n- name: b\n--- some-value\n--- {}\n")
+
+	err = storage.Upsert("kind", "ConfigMap")
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("kind")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "ConfigMap")
+
+	path := ".test/db-keep-empty.yaml"
+	defer os.Remove(path)
+	state, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = state.DeleteAll(true).
+		KeepEmptyDocs(true).
+		ImportFrom(strings.NewReader(mixedDocs))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(state.GetAllData()), 5)
+
+	err = state.Read()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(state.GetAllData()), 5)
+}
+
+// TestNullDocNames run unit tests on keeping the names of documents
+// that follow a null document after a write
+func TestNullDocNames(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-null-doc-names.yaml"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte("a: 1\n---\n---\nb: 2\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 3)
+
+	err = storage.SetName("b", 2)
+	assert.Equal(t, err, nil)
+
+	err = storage.Switch(0)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("x", 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+	assert.Equal(t, storage.ListDocs(), []db.DocRef{{Name: "b", Index: 1}})
+
+	err = storage.SwitchDoc("b")
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("b")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 2)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestConvertFormats run unit tests on format conversion
+func TestConvertFormats(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := db.Convert(
+		strings.NewReader("---\n---\nkind: Service\nspec:\n  ports: [80, 443]\n---\nkind: Deployment\n"),
+		db.CodecFor(".yaml"),
+		&buf,
+		db.CodecFor(".json"),
+	)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "{\"kind\":\"Service\",\"spec\":{\"ports\":[80,443]}}\n{\"kind\":\"Deployment\"}\n")
+
+	in := buf.String()
+	buf.Reset()
+	err = db.Convert(strings.NewReader(in), db.JSONCodec{}, &buf, db.YAMLCodec{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "kind: Service\nspec:\n  ports:\n  - 80\n  - 443\n---\nkind: Deployment\n")
+
+	buf.Reset()
+	err = db.Convert(strings.NewReader("a = 1\n"), db.TOMLCodec{}, &buf, db.EnvCodec{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "a=1\n")
+
+	err = db.Convert(strings.NewReader("{"), db.JSONCodec{}, &buf, db.YAMLCodec{})
+	assert.NotEqual(t, err, nil)
+
+	err = db.Convert(strings.NewReader(in), db.JSONCodec{}, &buf, db.TOMLCodec{})
+	assert.NotEqual(t, err, nil)
+}
+
+// TestMixedFormats run unit tests on merging and importing
+// files with a different format than the storage
+func TestMixedFormats(t *testing.T) {
+	t.Parallel()
+
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+
+	override := ".test/override.json"
+	defer os.Remove(override)
+	err = ioutil.WriteFile(override, []byte(`{"spec": {"replicas": 3}, "kind": "Deployment"}`), 0600)
+	assert.Equal(t, err, nil)
+
+	docs := ".test/import.toml"
+	defer os.Remove(docs)
+	err = ioutil.WriteFile(docs, []byte("kind = \"ConfigMap\"\n[data]\nkey = \"value\"\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	path := ".test/db-mixed.yaml"
+	defer os.Remove(path)
+	os.Remove(path)
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("metadata.name", "web")
+	assert.Equal(t, err, nil)
+
+	err = storage.MergeDBs(override)
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	val, err = storage.GetPath("metadata.name")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "web")
+
+	err = storage.ImportDocs(docs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	err = storage.Switch(1)
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("data.key")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "value")
+
+	err = storage.MergeFrom(strings.NewReader("labels.app=web\n"), db.PropertiesCodec{})
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("labels.app")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "web")
+
+	err = storage.ImportFrom(strings.NewReader(`{"kind": "Secret"}`), db.JSONCodec{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 3)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+---
+data:
+  key: value
+kind: ConfigMap
+labels:
+  app: web
+---
+kind: Secret
+`)
+}
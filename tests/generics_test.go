@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestGenerics run unit tests on typed getters
+func TestGenerics(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert(
+		"spec",
+		map[string]interface{}{
+			"replicas": 3,
+			"ratio":    0.5,
+			"name":     "web",
+			"enabled":  true,
+			"ports":    []int{80, 443},
+			"labels":   map[string]string{"app": "web", "tier": "frontend"},
+			"limits":   map[string]int{"cpu": 2, "memory": 512},
+			"containers": []map[string]string{
+				{"name": "web", "image": "nginx"},
+			},
+		},
+	)
+	assert.Equal(t, err, nil)
+
+	replicas, err := db.Get[int](storage, "spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replicas, 3)
+
+	replicas64, err := db.Get[int64](storage, "spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replicas64, int64(3))
+
+	replicasFloat, err := db.Get[float64](storage, "spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replicasFloat, float64(3))
+
+	ratio, err := db.Get[float64](storage, "spec.ratio")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ratio, 0.5)
+
+	_, err = db.Get[int](storage, "spec.ratio")
+	assert.NotEqual(t, err, nil)
+
+	var typeErr *db.TypeError
+	_, err = db.Get[int](storage, "spec.name")
+	assert.Equal(t, errors.As(err, &typeErr), true)
+	assert.Equal(t, typeErr.Path, "spec.name")
+	assert.Equal(t, typeErr.Type, "int")
+
+	enabled, err := db.Get[bool](storage, "spec.enabled")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, enabled, true)
+
+	ports, err := db.Get[[]int](storage, "spec.ports")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ports, []int{80, 443})
+
+	portsFloat, err := db.Get[[]float64](storage, "spec.ports")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, portsFloat, []float64{80, 443})
+
+	labels, err := db.Get[map[string]string](storage, "spec.labels")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, labels, map[string]string{"app": "web", "tier": "frontend"})
+
+	limits, err := db.Get[map[string]int64](storage, "spec.limits")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, limits, map[string]int64{"cpu": 2, "memory": 512})
+
+	_, err = db.Get[map[string]int](storage, "spec.labels")
+	assert.Equal(t, errors.As(err, &typeErr), true)
+
+	containers, err := db.Get[[]map[string]string](storage, "spec.containers")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, containers[0]["image"], "nginx")
+
+	type container struct {
+		Name  string `yaml:"name"`
+		Image string `yaml:"image"`
+	}
+	structs, err := db.Get[[]container](storage, "spec.containers")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, structs, []container{{Name: "web", Image: "nginx"}})
+
+	_, err = db.Get[[]string](storage, "spec.missing")
+	assert.NotEqual(t, err, nil)
+
+	assert.Equal(t, db.GetOr(storage, "spec.replicas", 1), 3)
+	assert.Equal(t, db.GetOr(storage, "spec.missing", 1), 1)
+	assert.Equal(t, db.GetOr(storage, "spec.name", 1), 1)
+	assert.Equal(t, db.GetOr(storage, "spec.name", "default"), "web")
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestIncrement run unit tests on Increment, Append and Prepend
+func TestIncrement(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-increment.yaml"
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.Increment("counters.releases", 1)
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("counters.releases")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+
+	err = storage.Increment("counters.releases", 2)
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("counters.releases")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	err = storage.Increment("counters.releases", 0.5)
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("counters.releases")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3.5)
+
+	err = storage.Increment("counters.releases", "1")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("counters.name", "some-name")
+	assert.Equal(t, err, nil)
+
+	err = storage.Increment("counters.name", 1)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Append("history", "v0.1.0")
+	assert.Equal(t, err, nil)
+
+	err = storage.Append("history", "v0.2.0", "v0.3.0")
+	assert.Equal(t, err, nil)
+
+	err = storage.Prepend("history", "v0.0.1")
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("history")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []interface{}{"v0.0.1", "v0.1.0", "v0.2.0", "v0.3.0"})
+
+	err = storage.Append("history", map[string]string{"key-1": "value-1"})
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("history.[4].key-1")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "value-1")
+
+	err = storage.Append("counters.name", "v0.1.0")
+	assert.NotEqual(t, err, nil)
+
+	err = os.Remove(path)
+	assert.Equal(t, err, nil)
+}
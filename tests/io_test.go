@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestImportExport run unit tests on reader/writer import and export
+func TestImportExport(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(
+		"---\nkind: Deployment\nname: a\n---\nkind: Service\nname: b\n",
+	))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	err = storage.ImportFrom(strings.NewReader("a: [b"))
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	var buf bytes.Buffer
+	err = storage.ExportTo(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "kind: Deployment\nname: a\n---\nkind: Service\nname: b\n")
+
+	buf.Reset()
+	err = storage.ExportTo(&buf, db.ExportOpts{Filter: db.ByPath("kind", "Service")})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "kind: Service\nname: b\n")
+
+	buf.Reset()
+	err = storage.ExportDoc(0, &buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "kind: Deployment\nname: a\n")
+
+	err = storage.ExportDoc(2, &buf)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Switch(1)
+	assert.Equal(t, err, nil)
+
+	err = storage.MergeFrom(strings.NewReader("spec:\n  type: ClusterIP\n"))
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("spec.type")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "ClusterIP")
+
+	err = storage.MergeFrom(strings.NewReader("spec: [a"))
+	assert.NotEqual(t, err, nil)
+}
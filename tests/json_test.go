@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestJSON run unit tests on json storage files
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-state.json"
+	defer os.Remove(path)
+	os.Remove(path)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("some.path", map[string]interface{}{
+		"key":   "value",
+		"count": 1,
+		"ratio": 0.5,
+		"list":  []int{1, 2},
+	})
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `{
+  "some": {
+    "path": {
+      "count": 1,
+      "key": "value",
+      "list": [
+        1,
+        2
+      ],
+      "ratio": 0.5
+    }
+  }
+}
+`)
+
+	err = storage.Read()
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("some.path.count")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+
+	val, err = storage.GetPath("some.path.ratio")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 0.5)
+
+	val, err = storage.GetPath("some.path.list.[1]")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 2)
+
+	err = storage.AddDoc()
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("kind", "Service")
+	assert.Equal(t, err, nil)
+
+	f, err = ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `{"some":{"path":{"count":1,"key":"value","list":[1,2],"ratio":0.5}}}
+{"kind":"Service"}
+`)
+
+	state, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(state.GetAllData()), 2)
+
+	err = state.Switch(1)
+	assert.Equal(t, err, nil)
+
+	val, err = state.GetPath("kind")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "Service")
+
+	ndpath := ".test/import.ndjson"
+	defer os.Remove(ndpath)
+	err = ioutil.WriteFile(ndpath, []byte("{\"kind\":\"Deployment\"}\n{\"kind\":\"Service\"}\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	yamlState, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = yamlState.DeleteAll(true).ImportDocs(ndpath)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(yamlState.GetAllData()), 2)
+
+	val, err = yamlState.GetPath("kind")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "Deployment")
+
+	err = ioutil.WriteFile(ndpath, []byte("{\"kind\":"), 0600)
+	assert.Equal(t, err, nil)
+
+	err = yamlState.ImportDocs(ndpath)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(yamlState.GetAllData()), 2)
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestKube run unit tests on Kubernetes manifests validation
+func TestKube(t *testing.T) {
+	t.Parallel()
+
+	kube, err := db.NewKubeValidatorFactory("testdata/kube", true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, kube.Kinds(), []string{"apps/v1/deployment", "example.com/v1/widget", "v1/service"})
+
+	_, err = db.NewKubeValidatorFactory("testdata/missing", true)
+	assert.NotEqual(t, err, nil)
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNames("kind", "metadata.name")
+	assert.Equal(t, err, nil)
+
+	storage.SetKubeValidator(kube)
+
+	// HorizontalPodAutoscaler and PodDisruptionBudget have no schema,
+	// which strict mode reports
+	violations := storage.Validate()
+	assert.Equal(t, len(violations), 4)
+	for _, j := range violations {
+		assert.Equal(t, j.Path, "")
+	}
+
+	err = storage.SwitchDoc("deployment/listener-svc")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.replicas", 2)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.strategy.rollingUpdate.maxUnavailable", "25%")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.replica", 2)
+	var vErr *db.ValidationError
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, vErr.Violations[0].Path, "spec")
+
+	_, err = storage.GetPath("spec.replica")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("spec.template.spec.containers", []map[string]interface{}{{"name": "web", "ports": []map[string]string{{"containerPort": "http"}}}})
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, vErr.Violations[0].Path, "spec.template.spec.containers.[0].ports.[0].containerPort")
+
+	// Violations that the documents already have, such as kinds
+	// without a schema, do not reject a change
+	err = storage.UpsertGlobal("metadata.labels.team", "platform")
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("horizontalpodautoscaler/listener-svc")
+	assert.Equal(t, err, nil)
+	err = storage.Upsert("spec.maxReplicas", 6)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 4)
+
+	err = storage.UpsertGlobal("spec.replica", 2)
+	assert.NotEqual(t, err, nil)
+
+	storage.SetKubeValidator(nil)
+	err = storage.UpsertGlobal("metadata.labels.team", "platform")
+	assert.Equal(t, err, nil)
+
+	loose, err := db.NewKubeValidatorFactory("testdata/kube", false)
+	assert.Equal(t, err, nil)
+
+	// Kinds without a schema are not reported in non strict mode
+	storage.SetKubeValidator(loose)
+	assert.Equal(t, len(storage.Validate()), 0)
+
+	err = storage.SwitchDoc("deployment/listener-svc")
+	assert.Equal(t, err, nil)
+	err = storage.Upsert("spec.replica", 2)
+	assert.Equal(t, err, nil)
+
+	err = storage.AddDoc()
+	assert.Equal(t, err, nil)
+
+	storage.SetKubeValidator(kube)
+	err = storage.UpsertMany(
+		map[string]interface{}{
+			"apiVersion":    "example.com/v1",
+			"kind":          "Widget",
+			"metadata.name": "widget",
+			"spec.size":     0,
+		},
+	)
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, vErr.Violations[0].Path, "spec.size")
+
+	err = storage.UpsertMany(
+		map[string]interface{}{
+			"apiVersion":          "example.com/v1",
+			"kind":                "Widget",
+			"metadata.name":       "widget",
+			"metadata.any.field":  "value",
+			"spec.size":           1,
+			"spec.port":           8080,
+			"spec.owner":          "team-a",
+			"spec.extra.anything": true,
+		},
+	)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.port", "http")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.port", true)
+	assert.NotEqual(t, err, nil)
+}
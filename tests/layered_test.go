@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestLayeredStorage run unit tests on layered storages
+func TestLayeredStorage(t *testing.T) {
+	t.Parallel()
+
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+
+	basePath := ".test/layer-base.yaml"
+	defer os.Remove(basePath)
+	baseFile := `app:
+  name: web
+  replicas: 1
+  ports: [80, 443]
+  env:
+    LOG: info
+    MODE: base
+`
+	err = ioutil.WriteFile(basePath, []byte(baseFile), 0600)
+	assert.Equal(t, err, nil)
+
+	prodPath := ".test/layer-prod.yaml"
+	defer os.Remove(prodPath)
+	err = ioutil.WriteFile(prodPath, []byte(`app:
+  replicas: 3
+  ports: [8443]
+  env:
+    MODE: prod
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	base, err := db.NewStorageFactory(basePath)
+	assert.Equal(t, err, nil)
+
+	prod, err := db.NewStorageFactory(prodPath)
+	assert.Equal(t, err, nil)
+
+	local, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	layers := db.NewLayeredStorage(base, prod, local)
+
+	val, err := layers.GetPath("app.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	val, err = layers.GetPath("app.ports")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, []interface{}{8443})
+
+	val, err = layers.GetPath("app.env")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, map[interface{}]interface{}{"LOG": "info", "MODE": "prod"})
+
+	err = layers.Upsert("app.env.LOG", "debug")
+	assert.Equal(t, err, nil)
+
+	val, err = layers.GetPath("app.env.LOG")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "debug")
+
+	for k, v := range map[string][]int{
+		"app.name":      {0},
+		"app.replicas":  {1},
+		"app.ports":     {1},
+		"app.env":       {0, 1, 2},
+		"app.env.LOG":   {2},
+		"app.env.MODE":  {1},
+		"app.ports.[0]": {1},
+	} {
+		found, err := layers.Explain(k)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, found, v)
+	}
+
+	_, err = layers.Explain("app.ports.[1]")
+	assert.NotEqual(t, err, nil)
+
+	err = layers.SetWriteLayer(1)
+	assert.Equal(t, err, nil)
+
+	err = layers.Upsert("app.replicas", 5)
+	assert.Equal(t, err, nil)
+
+	err = layers.SetWriteLayer(3)
+	assert.NotEqual(t, err, nil)
+
+	f, err := ioutil.ReadFile(prodPath)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `app:
+  env:
+    MODE: prod
+  ports:
+  - 8443
+  replicas: 5
+`)
+
+	f, err = ioutil.ReadFile(basePath)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), baseFile)
+
+	err = layers.Delete("app.replicas")
+	assert.Equal(t, err, nil)
+
+	val, err = layers.GetPath("app.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+
+	layer, err := layers.Layer(0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, layer, base)
+
+	_, err = layers.Layer(-1)
+	assert.NotEqual(t, err, nil)
+}
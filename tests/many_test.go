@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestMany run unit tests on UpsertMany and DeleteMany
+func TestMany(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-many.yaml"
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.UpsertMany(
+		map[string]interface{}{
+			"metadata.labels.version":           "v0.3.0",
+			"metadata.labels":                   map[string]string{"app": "web"},
+			"spec.selector.matchLabels.version": "v0.3.0",
+			"spec.replicas":                     3,
+		},
+	)
+	assert.Equal(t, err, nil)
+
+	for k, v := range map[string]interface{}{
+		"metadata.labels.version":           "v0.3.0",
+		"metadata.labels.app":               "web",
+		"spec.selector.matchLabels.version": "v0.3.0",
+		"spec.replicas":                     3,
+	} {
+		val, err := storage.GetPath(k)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, val, v)
+	}
+
+	err = storage.UpsertMany(
+		map[string]interface{}{
+			"spec.replicas": 4,
+			"spec..name":    "invalid",
+		},
+	)
+	assert.NotEqual(t, err, nil)
+
+	val, err := storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	err = storage.DeleteMany([]string{"spec.replicas", "metadata.labels.missing"})
+	assert.NotEqual(t, err, nil)
+
+	val, err = storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	err = storage.DeleteMany([]string{"spec.replicas", "metadata.labels.app"})
+	assert.Equal(t, err, nil)
+
+	_, err = storage.GetPath("spec.replicas")
+	assert.NotEqual(t, err, nil)
+	_, err = storage.GetPath("metadata.labels.app")
+	assert.NotEqual(t, err, nil)
+
+	val, err = storage.GetPath("metadata.labels.version")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "v0.3.0")
+
+	err = os.Remove(path)
+	assert.Equal(t, err, nil)
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestMerge3 run unit tests on three-way merges
+func TestMerge3(t *testing.T) {
+	t.Parallel()
+
+	base := map[interface{}]interface{}{
+		"name":     "web",
+		"replicas": 1,
+		"ports":    []interface{}{80},
+		"env":      map[interface{}]interface{}{"LOG": "info", "MODE": "dev"},
+	}
+	ours := map[string]interface{}{
+		"name":     "web",
+		"replicas": 2,
+		"ports":    []interface{}{80, 443},
+		"env":      map[string]interface{}{"LOG": "debug", "MODE": "dev"},
+	}
+	theirs := map[interface{}]interface{}{
+		"name":     "api",
+		"replicas": 3,
+		"ports":    []interface{}{80},
+		"env":      map[interface{}]interface{}{"LOG": "info"},
+	}
+
+	merged, conflicts, err := db.Merge3(base, ours, theirs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, merged, map[interface{}]interface{}{
+		"name":     "api",
+		"replicas": 2,
+		"ports":    []interface{}{80, 443},
+		"env":      map[interface{}]interface{}{"LOG": "debug"},
+	})
+	assert.Equal(t, conflicts, []db.Conflict{
+		{Path: "replicas", Base: 1, Ours: 2, Theirs: 3},
+	})
+
+	merged, _, err = db.Merge3(base, ours, theirs, db.MergeTheirs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, merged.(map[interface{}]interface{})["replicas"], 3)
+
+	merged, _, err = db.Merge3(base, ours, theirs, func(c db.Conflict) (interface{}, bool, error) {
+		return c.Ours.(int) + c.Theirs.(int), true, nil
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, merged.(map[interface{}]interface{})["replicas"], 5)
+
+	_, _, err = db.Merge3(base, ours, theirs, func(c db.Conflict) (interface{}, bool, error) {
+		return nil, false, errors.New("unresolved")
+	})
+	assert.NotEqual(t, err, nil)
+
+	deleted := map[interface{}]interface{}{"name": "web", "ports": []interface{}{80}}
+	merged, conflicts, err = db.Merge3(base, ours, deleted, db.MergeTheirs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, merged, map[interface{}]interface{}{
+		"name":  "web",
+		"ports": []interface{}{80, 443},
+	})
+	assert.Equal(t, len(conflicts), 2)
+	assert.Equal(t, conflicts[0].Path, "env")
+	assert.Equal(t, conflicts[0].TheirsDeleted, true)
+	assert.Equal(t, conflicts[1].Path, "replicas")
+}
+
+// TestMerge3Storage run unit tests on three-way merges of storages
+func TestMerge3Storage(t *testing.T) {
+	t.Parallel()
+
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+
+	basePath := ".test/merge3-base.yaml"
+	defer os.Remove(basePath)
+	err = ioutil.WriteFile(basePath, []byte(`kind: Deployment
+spec:
+  replicas: 1
+  image: web:1
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	theirsPath := ".test/merge3-theirs.yaml"
+	defer os.Remove(theirsPath)
+	err = ioutil.WriteFile(theirsPath, []byte(`kind: Deployment
+spec:
+  replicas: 1
+  image: web:2
+---
+kind: Service
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	path := ".test/db-merge3.yaml"
+	defer os.Remove(path)
+	err = ioutil.WriteFile(path, []byte(`kind: Deployment
+spec:
+  replicas: 3
+  image: web:1
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	conflicts, err := storage.Merge3Files(basePath, theirsPath)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(conflicts), 0)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `kind: Deployment
+spec:
+  image: web:2
+  replicas: 3
+---
+kind: Service
+`)
+
+	base, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+	err = base.DeleteAll(true).ImportFrom(strings.NewReader("spec:\n  replicas: 3\n"))
+	assert.Equal(t, err, nil)
+
+	theirs, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+	err = theirs.DeleteAll(true).ImportFrom(strings.NewReader("spec:\n  replicas: 4\n"))
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("spec.replicas", 5)
+	assert.Equal(t, err, nil)
+
+	conflicts, err = storage.Merge3(base, theirs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, conflicts, []db.Conflict{
+		{Path: "[0].spec.replicas", Base: 3, Ours: 5, Theirs: 4},
+	})
+
+	val, err := storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 5)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	err = storage.SetName("svc", 1)
+	assert.Equal(t, err, nil)
+	err = storage.SetSchema(db.AllDocs, []byte(`{"properties": {"spec": {"properties": {"replicas": {"maximum": 5}}}}}`))
+	assert.Equal(t, err, nil)
+
+	err = theirs.DeleteAll(true).ImportFrom(strings.NewReader("kind: Deployment\nspec:\n  image: web:2\n  replicas: 9\n"))
+	assert.Equal(t, err, nil)
+
+	_, err = storage.Merge3(storage, theirs)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 2)
+
+	i, exists := storage.Lib()["svc"]
+	assert.Equal(t, exists, true)
+	assert.Equal(t, i, 1)
+	assert.Equal(t, storage.GetAD(), 1)
+
+	err = storage.Switch(0)
+	assert.Equal(t, err, nil)
+	val, err = storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 5)
+
+	// Violations that the documents already have do not reject a merge
+	err = storage.SetSchema(db.AllDocs, []byte(`{"required": ["missing"]}`))
+	assert.Equal(t, err, nil)
+	err = theirs.DeleteAll(true).ImportFrom(strings.NewReader("kind: Deployment\nspec:\n  image: web:2\n  replicas: 4\n"))
+	assert.Equal(t, err, nil)
+
+	_, err = storage.Merge3(storage, theirs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 1)
+
+	_, err = storage.Merge3Files(".test/missing.yaml", theirsPath)
+	assert.NotEqual(t, err, nil)
+}
@@ -62,10 +62,10 @@ func TestMultiDoc(t *testing.T) {
 	}
 
 	for _, j := range storage.ListDocs() {
-		if strings.HasPrefix(j, "horizontalpodautoscaler/") {
+		if strings.HasPrefix(j.Name, "horizontalpodautoscaler/") {
 			continue
 		}
-		err = storage.SwitchDoc(j)
+		err = storage.SwitchDoc(j.Name)
 		assert.Equal(t, err, nil)
 
 		val, err := storage.GetPath("metadata.labels.version")
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestRender run unit tests on rendering templates
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(`kind: Service
+metadata:
+  name: web
+spec:
+  port: 8080
+---
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+    tier: frontend
+`))
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.metadata.name}}")
+	assert.Equal(t, err, nil)
+
+	var buf bytes.Buffer
+	err = storage.Render(`{{range .Docs}}{{.kind}}
+{{end}}port={{getPath "spec.port"}}
+web={{getPath "metadata.labels.app" "deployment/web"}}
+first={{getPath "kind" 0}}
+doc={{index .Names "service/web"}}
+labels:
+{{getPath "metadata.labels" 1 | toYaml | indent 2}}
+`, &buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `Service
+Deployment
+port=8080
+web=web
+first=Service
+doc=0
+labels:
+  app: web
+  tier: frontend
+`)
+
+	buf.Reset()
+	err = storage.Render(`{{getPath "spec.missing"}}`, &buf)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, buf.Len(), 0)
+
+	err = storage.Render(`{{getPath "kind" "missing/doc"}}`, &buf)
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Render(`{{`, &buf)
+	assert.NotEqual(t, err, nil)
+
+	path := ".test/render.tmpl"
+	defer os.Remove(path)
+	err = os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte("PORT={{.Doc.spec.port}}\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	err = storage.RenderFile(path, &buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), "PORT=8080\n")
+
+	err = storage.RenderFile(".test/missing.tmpl", &buf)
+	assert.NotEqual(t, err, nil)
+}
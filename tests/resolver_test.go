@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestResolver run unit tests on expanding references
+func TestResolver(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).ImportFrom(strings.NewReader(`kind: Service
+metadata:
+  name: web
+spec:
+  port: 8080
+---
+kind: Deployment
+metadata:
+  name: web
+spec:
+  host: ${DBY_TEST_HOST}
+  url: http://${DBY_TEST_HOST}:${doc:service/web:spec.port}/${ref:spec.path}
+  path: ${DBY_TEST_PATH:-api}
+  port: ${doc:service/web:spec.port}
+  literal: $${DBY_TEST_HOST}
+  env:
+  - ${DBY_TEST_UNSET}
+  self: ${ref:spec.self}
+  spec: ${doc:service/web:spec}
+  nested: a ${doc:service/web:spec}
+`))
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNamesTemplate("{{.kind}}/{{.metadata.name}}")
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("deployment/web")
+	assert.Equal(t, err, nil)
+
+	val, err := storage.GetPath("spec.url")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "http://${DBY_TEST_HOST}:${doc:service/web:spec.port}/${ref:spec.path}")
+
+	storage.SetResolver(&db.Resolver{
+		LookupEnv: func(k string) (string, bool) {
+			if k == "DBY_TEST_HOST" {
+				return "localhost", true
+			}
+			return "", false
+		},
+	})
+
+	val, err = storage.GetPath("spec.url")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "http://localhost:8080/api")
+
+	val, err = storage.GetPath("spec.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 8080)
+
+	val, err = storage.GetPath("spec.literal")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "${DBY_TEST_HOST}")
+
+	val, err = storage.GetPath("spec.spec")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, map[interface{}]interface{}{"port": 8080})
+
+	val, err = storage.GetFirst("host")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "localhost")
+
+	port, err := db.Get[int](storage, "spec.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, port, 8080)
+
+	val, err = storage.GetPathRaw("spec.port")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "${doc:service/web:spec.port}")
+
+	val, err = storage.GetFirstRaw("host")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "${DBY_TEST_HOST}")
+
+	assert.Equal(t, storage.GetPathGlobal("spec.port"), map[int]interface{}{0: 8080, 1: 8080})
+
+	for _, k := range []string{"spec.env", "spec.self", "spec.nested", "spec"} {
+		_, err = storage.GetPath(k)
+		assert.NotEqual(t, err, nil)
+	}
+
+	err = storage.Upsert("spec.env", "${DBY_TEST_UNSET:-none}")
+	assert.Equal(t, err, nil)
+
+	val, err = storage.GetPath("spec.env")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "none")
+
+	val, err = storage.GetPathRaw("spec.env")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "${DBY_TEST_UNSET:-none}")
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+const testSchema = `
+$schema: "http://json-schema.org/draft-07/schema#"
+type: object
+required: [kind, metadata]
+properties:
+  kind:
+    type: string
+  metadata:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+  spec:
+    type: object
+    properties:
+      replicas:
+        type: integer
+        minimum: 1
+      ports:
+        type: array
+        items:
+          type: object
+          properties:
+            port:
+              type: integer
+`
+
+// TestSchema run unit tests on JSON Schema validation
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.SetSchema(db.AllDocs, []byte("type: [object"))
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetSchema(db.AllDocs, []byte(testSchema))
+	assert.Equal(t, err, nil)
+
+	violations := storage.Validate()
+	assert.Equal(t, len(violations), 1)
+
+	err = storage.UpsertMany(
+		map[string]interface{}{
+			"kind":          "Deployment",
+			"metadata.name": "web",
+			"spec.replicas": 2,
+			"spec.ports":    []map[string]int{{"port": 80}},
+		},
+	)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 0)
+
+	err = storage.Upsert("spec.replicas", 0)
+	assert.NotEqual(t, err, nil)
+
+	var vErr *db.ValidationError
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, len(vErr.Violations), 1)
+	assert.Equal(t, vErr.Violations[0].Path, "spec.replicas")
+
+	val, err := storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 2)
+
+	err = storage.Upsert("spec.ports.[0]", map[string]string{"port": "http"})
+	assert.NotEqual(t, err, nil)
+
+	err = storage.Upsert("spec.ports", []map[string]string{{"port": "http"}})
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, vErr.Violations[0].Path, "spec.ports.[0].port")
+
+	err = storage.Delete("metadata.name")
+	assert.NotEqual(t, err, nil)
+
+	val, err = storage.GetPath("metadata.name")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "web")
+
+	path := ".test/db-schema-merge.yaml"
+	err = os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = os.WriteFile(path, []byte("spec:\n  replicas: -1\n"), 0600)
+	assert.Equal(t, err, nil)
+
+	err = storage.MergeDBs(path)
+	assert.Equal(t, errors.As(err, &vErr), true)
+
+	val, err = storage.GetPath("spec.replicas")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 2)
+
+	err = os.Remove(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 9)
+
+	storage.UnsetSchema(db.AllDocs)
+	err = storage.SetSchema("deployment/listener-svc", []byte(`{"properties": {"kind": {"const": "Deployment"}}}`))
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNames("kind", "metadata.name")
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("deployment/listener-svc")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("kind", "Service")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SwitchDoc("service/listener-svc")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("kind", "Service")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetSchema(db.AllDocs, []byte(`{"required": ["missing"]}`))
+	assert.Equal(t, err, nil)
+
+	err = storage.ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, len(storage.GetAllData()), 9)
+	assert.Equal(t, len(storage.Validate()), 9)
+}
+
+// TestSchemaSelector run unit tests on schemas that target a label selector
+func TestSchemaSelector(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	listeners, err := storage.Select("app=listener-svc")
+	assert.Equal(t, err, nil)
+
+	namespace := []byte(`{"properties": {"metadata": {"properties": {"namespace": {"const": "echoserver"}}}}}`)
+	err = storage.SetSchema("app=listener-svc", namespace)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 0)
+
+	err = storage.Switch(listeners[0])
+	assert.Equal(t, err, nil)
+	err = storage.Upsert("metadata.namespace", "sysdebug")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.SetSchema("app in (listener-svc,caller-svc)", namespace)
+	assert.Equal(t, err, nil)
+
+	violations := storage.Validate()
+	assert.NotEqual(t, len(violations), 0)
+	for _, j := range violations {
+		err = storage.Switch(j.Doc)
+		assert.Equal(t, err, nil)
+		val, err := storage.GetPath("metadata.labels.app")
+		assert.Equal(t, err, nil)
+		assert.Equal(t, val, "caller-svc")
+	}
+
+	storage.UnsetSchema("app in (listener-svc,caller-svc)")
+	assert.Equal(t, len(storage.Validate()), 0)
+
+	err = storage.SetSchema("app in (", namespace)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 0)
+}
+
+// TestSchemaExistingViolations run unit tests on changing documents
+// that already have violations
+func TestSchemaExistingViolations(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.UpsertMany(map[string]interface{}{"a": "x", "b": "y"})
+	assert.Equal(t, err, nil)
+
+	err = storage.SetSchema(db.AllDocs, []byte(`{"properties": {"a": {"type": "integer"}, "b": {"type": "integer"}}}`))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 2)
+
+	err = storage.Upsert("a", 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.Validate()), 1)
+
+	err = storage.Upsert("c", 1)
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("b", "z")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("a", "x")
+	var vErr *db.ValidationError
+	assert.Equal(t, errors.As(err, &vErr), true)
+	assert.Equal(t, len(vErr.Violations), 1)
+	assert.Equal(t, vErr.Violations[0].Path, "a")
+
+	val, err := storage.GetPath("a")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 1)
+}
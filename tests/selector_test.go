@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestSelector run unit tests on label selectors
+func TestSelector(t *testing.T) {
+	t.Parallel()
+
+	storage, err := db.NewStorageFactory()
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	for _, j := range []struct {
+		Selector string
+		Docs     []int
+	}{
+		{"", []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"app=listener-svc", []int{1, 2, 3}},
+		{"app==listener-svc", []int{1, 2, 3}},
+		{"app!=listener-svc", []int{0, 4, 5, 6, 7}},
+		{"app", []int{1, 2, 3, 5, 6, 7}},
+		{"!app", []int{0, 4}},
+		{"app in (listener-svc, caller-svc)", []int{1, 2, 3, 5, 6, 7}},
+		{"app notin (listener-svc)", []int{0, 4, 5, 6, 7}},
+		{"app=caller-svc,version=v0.2.0", []int{5, 6, 7}},
+		{"app in (caller-svc),version notin (v0.2.0)", []int{}},
+		{"app=", []int{}},
+	} {
+		docs, err := storage.Select(j.Selector)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, docs, j.Docs, j.Selector)
+	}
+
+	for _, j := range []string{
+		"app in (listener-svc",
+		"app in listener-svc)",
+		"app=web,",
+		"app=web app",
+		"=web",
+		"app in ((web))",
+	} {
+		_, err := storage.Select(j)
+		assert.NotEqual(t, err, nil, j)
+	}
+
+	err = storage.UpdateGlobal("metadata.labels.version", "v0.3.0", "app=caller-svc")
+	assert.Equal(t, err, nil)
+
+	versions := storage.GetPathGlobal("metadata.labels.version")
+	assert.Equal(t, len(versions), 6)
+	for i, j := range versions {
+		if i < 4 {
+			assert.Equal(t, j, "v0.1.1")
+			continue
+		}
+		assert.Equal(t, j, "v0.3.0")
+	}
+
+	versions = storage.GetPathGlobal("metadata.labels.version", "app=caller-svc")
+	assert.Equal(t, len(versions), 3)
+
+	versions = storage.GetPathGlobal("metadata.labels.version", "app in (")
+	assert.Equal(t, len(versions), 0)
+
+	err = storage.UpsertGlobal("metadata.labels.team", "platform", "!app")
+	assert.Equal(t, err, nil)
+
+	docs, err := storage.Select("team=platform")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, docs, []int{0, 4})
+
+	err = storage.UpsertGlobal("metadata.labels.team", "platform", "app in (")
+	assert.NotEqual(t, err, nil)
+
+	assert.Equal(t, len(storage.GetFirstGlobal("team", "team")), 2)
+	assert.Equal(t, len(storage.FindKeysGlobal("version", "app=listener-svc")), 3)
+
+	err = storage.DeleteGlobal("metadata.labels.version", "app=listener-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetPathGlobal("metadata.labels.version")), 3)
+
+	err = storage.DeleteGlobal("metadata.labels.team")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetPathGlobal("metadata.labels.team")), 0)
+
+	err = storage.DeleteGlobal("metadata.labels.team")
+	assert.NotEqual(t, err, nil)
+
+	err = storage.DeleteGlobal("metadata.labels.version", "app=listener-svc")
+	assert.NotEqual(t, err, nil)
+
+	storage.SetLabelsPath("spec.selector")
+	docs, err = storage.Select("app=listener-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, docs, []int{2})
+
+	storage.SetLabelsPath("spec.selector.matchLabels")
+	docs, err = storage.Select("app")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, docs, []int{1, 3, 5, 7})
+}
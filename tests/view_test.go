@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+// TestView run unit tests on document views
+func TestView(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-view.yaml"
+	storage, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+
+	err = storage.DeleteAll(true).
+		ImportDocs("../docs/examples/manifests/deployment.yaml")
+	assert.Equal(t, err, nil)
+
+	err = storage.SetNames("kind", "metadata.name")
+	assert.Equal(t, err, nil)
+
+	err = storage.Switch(3)
+	assert.Equal(t, err, nil)
+
+	deployments, err := storage.View(db.ByPath("kind", "Deployment"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, deployments.Parent(), storage)
+	assert.Equal(t, len(deployments.GetAllData()), 2)
+	assert.Equal(t, deployments.GetAD(), 0)
+	assert.Equal(t, deployments.ListDocs(), []db.DocRef{
+		{Name: "deployment/listener-svc", Index: 0},
+		{Name: "deployment/caller-svc", Index: 1},
+	})
+
+	err = deployments.UpsertGlobal("spec.replicas", 5)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, storage.GetAD(), 3)
+
+	replicas := storage.GetPathGlobal("spec.replicas")
+	assert.Equal(t, replicas, map[int]interface{}{1: 5, 5: 5})
+
+	reloaded, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, reloaded.GetPathGlobal("spec.replicas"), map[int]interface{}{1: 5, 5: 5})
+
+	err = deployments.SwitchDoc("deployment/caller-svc")
+	assert.Equal(t, err, nil)
+
+	err = deployments.SwitchDoc("service/caller-svc")
+	assert.NotEqual(t, err, nil)
+
+	err = deployments.UpsertMany(map[string]interface{}{"spec.paused": true})
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("deployment/caller-svc")
+	assert.Equal(t, err, nil)
+	val, err := storage.GetPath("spec.paused")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, true)
+
+	web, err := storage.View(db.ByNames("service/listener-svc"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(web.GetAllData()), 1)
+
+	_, err = storage.View(db.ByNames("service/missing"))
+	assert.NotEqual(t, err, nil)
+
+	err = web.Upsert("spec.type", "NodePort")
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("service/listener-svc")
+	assert.Equal(t, err, nil)
+	val, err = storage.GetPath("spec.type")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, "NodePort")
+
+	err = web.AddDoc()
+	assert.Equal(t, err, nil)
+	err = web.Upsert("kind", "ConfigMap")
+	assert.Equal(t, err, nil)
+	err = web.SetName("configmap/web", 1)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, len(storage.GetAllData()), 9)
+	i, ok := storage.LibIndex("configmap/web")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 8)
+
+	err = web.DeleteDocByName("service/listener-svc")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(storage.GetAllData()), 8)
+	_, ok = storage.LibIndex("service/listener-svc")
+	assert.Equal(t, ok, false)
+
+	// DeleteDoc does not write the local file
+	file, err := db.NewStorageFactory(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(file.GetAllData()), 9)
+
+	err = storage.Write()
+	assert.Equal(t, err, nil)
+	err = file.Read()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(file.GetAllData()), 8)
+
+	i, ok = storage.LibIndex("configmap/web")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, i, 7)
+
+	listeners, err := storage.View(db.BySelector("app=listener-svc"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(listeners.GetAllData()), 2)
+
+	_, err = storage.View(db.BySelector("app in ("))
+	assert.NotEqual(t, err, nil)
+
+	pdb, err := listeners.View(db.ByPath("kind", "PodDisruptionBudget"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(pdb.GetAllData()), 1)
+
+	err = pdb.Upsert("spec.minAvailable", 2)
+	assert.Equal(t, err, nil)
+
+	err = storage.SwitchDoc("poddisruptionbudget/listener-svc")
+	assert.Equal(t, err, nil)
+	val, err = storage.GetPath("spec.minAvailable")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 2)
+
+	err = storage.Upsert("spec.minAvailable", 3)
+	assert.Equal(t, err, nil)
+
+	err = pdb.Read()
+	assert.Equal(t, err, nil)
+	val, err = pdb.GetPath("spec.minAvailable")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	err = storage.SetSchema(db.AllDocs, []byte(`{"properties": {"spec": {"properties": {"minAvailable": {"maximum": 5}}}}}`))
+	assert.Equal(t, err, nil)
+
+	pdb, err = storage.View(db.ByPath("kind", "PodDisruptionBudget"))
+	assert.Equal(t, err, nil)
+	err = pdb.Upsert("spec.minAvailable", 6)
+	assert.NotEqual(t, err, nil)
+	val, err = storage.GetPath("spec.minAvailable")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, val, 3)
+
+	err = storage.SetSchema("app=web", []byte(`{"required": ["kind"]}`))
+	assert.Equal(t, err, nil)
+
+	pdb, err = storage.View(db.ByPath("kind", "PodDisruptionBudget"))
+	assert.Equal(t, err, nil)
+	pdb.UnsetSchema(db.AllDocs)
+	err = storage.Upsert("spec.minAvailable", 6)
+	assert.NotEqual(t, err, nil)
+
+	err = os.Remove(path)
+	assert.Equal(t, err, nil)
+}
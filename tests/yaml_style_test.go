@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/likexian/gokit/assert"
+	"github.com/ulfox/dby/db"
+)
+
+const styledDoc = `kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 2
+  containers:
+  - name: app
+    args: ["--port", "8080"]
+    env:
+    - {name: A, value: "yes"}
+  notes: |
+    first line
+    second line
+  empty: {}
+`
+
+// TestYAMLStyle run unit tests on the yaml output style
+func TestYAMLStyle(t *testing.T) {
+	t.Parallel()
+
+	docs, err := db.YAMLCodec{}.Decode(strings.NewReader(styledDoc))
+	assert.Equal(t, err, nil)
+
+	var buf bytes.Buffer
+	err = db.YAMLCodec{Style: db.YAMLStyle{
+		Indent:          4,
+		IndentSequences: true,
+		DocumentStart:   true,
+	}}.Encode(&buf, docs)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `---
+kind: Deployment
+metadata:
+    labels:
+        app: web
+    name: web
+spec:
+    containers:
+        - args:
+            - --port
+            - "8080"
+          env:
+            - name: A
+              value: "yes"
+          name: app
+    empty: {}
+    notes: |
+        first line
+        second line
+    replicas: 2
+`)
+
+	roundTrip, err := db.YAMLCodec{}.Decode(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, roundTrip, docs)
+
+	buf.Reset()
+	err = db.YAMLCodec{Style: db.YAMLStyle{
+		Quote: db.QuoteSingle,
+	}}.Encode(&buf, append(docs, "it's"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `kind: 'Deployment'
+metadata:
+  labels:
+    app: 'web'
+  name: 'web'
+spec:
+  containers:
+  - args:
+    - '--port'
+    - '8080'
+    env:
+    - name: 'A'
+      value: 'yes'
+    name: 'app'
+  empty: {}
+  notes: |
+    first line
+    second line
+  replicas: 2
+---
+'it''s'
+`)
+
+	roundTrip, err = db.YAMLCodec{}.Decode(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, roundTrip, append(docs, "it's"))
+
+	buf.Reset()
+	doc := map[interface{}]interface{}{
+		"text":   "the quick brown fox jumps over the lazy dog",
+		"quoted": "the quick brown fox: jumps over the lazy dog",
+		"list":   []interface{}{"the quick brown fox jumps over the lazy dog"},
+	}
+	err = db.YAMLCodec{Style: db.YAMLStyle{LineWidth: 20}}.Encode(&buf, []interface{}{doc})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `list:
+- the quick brown
+  fox jumps over the
+  lazy dog
+quoted: "the quick
+  brown fox: jumps
+  over the lazy dog"
+text: the quick
+  brown fox jumps
+  over the lazy dog
+`)
+
+	roundTrip, err = db.YAMLCodec{}.Decode(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, roundTrip, []interface{}{doc})
+
+	// Sequences are moved to their key's indentation along with
+	// the block scalars they hold
+	buf.Reset()
+	doc = map[interface{}]interface{}{
+		"steps": []interface{}{
+			map[interface{}]interface{}{
+				"run":  "key:\n- item\n",
+				"with": []interface{}{"a", []interface{}{"b", "c"}},
+			},
+		},
+		"empty": []interface{}{},
+	}
+	err = db.YAMLCodec{Style: db.YAMLStyle{Indent: 4}}.Encode(&buf, []interface{}{doc})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, buf.String(), `empty: []
+steps:
+- run: |
+    key:
+    - item
+  with:
+  - a
+  - - b
+    - c
+`)
+
+	roundTrip, err = db.YAMLCodec{}.Decode(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, roundTrip, []interface{}{doc})
+
+	buf.Reset()
+	doc = map[interface{}]interface{}{
+		"empty":    "",
+		"null":     nil,
+		"number":   "1.5",
+		"float":    2.0,
+		"bool":     "true",
+		"spaces":   " padded ",
+		"colon":    "a: b",
+		"comment":  "a #b",
+		"dash":     "-",
+		"control":  "a\x01b",
+		"keep":     "a\n\n",
+		"indented": "  a\nb",
+		"key: x":   1,
+		1:          "int key",
+	}
+	err = db.YAMLCodec{Style: db.YAMLStyle{Indent: 2}}.Encode(&buf, []interface{}{doc})
+	assert.Equal(t, err, nil)
+
+	roundTrip, err = db.YAMLCodec{}.Decode(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, roundTrip, []interface{}{doc})
+}
+
+// TestYAMLKeyOrder run unit tests on keeping the key order
+func TestYAMLKeyOrder(t *testing.T) {
+	t.Parallel()
+
+	path := ".test/db-key-order.yaml"
+	defer os.Remove(path)
+	err := os.MkdirAll(".test", 0700)
+	assert.Equal(t, err, nil)
+	err = ioutil.WriteFile(path, []byte(`kind: Service
+metadata:
+  name: web
+  annotations:
+    z: "1"
+    a: "2"
+spec:
+  ports:
+  - port: 80
+    name: http
+`), 0600)
+	assert.Equal(t, err, nil)
+
+	storage, err := db.NewStorageFactory(path, db.YAMLCodec{Style: db.YAMLStyle{
+		IndentSequences: true,
+		KeepKeyOrder:    true,
+	}})
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("metadata.namespace", "default")
+	assert.Equal(t, err, nil)
+
+	err = storage.Upsert("apiVersion", "v1")
+	assert.Equal(t, err, nil)
+
+	f, err := ioutil.ReadFile(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(f), `kind: Service
+metadata:
+  name: web
+  annotations:
+    z: "1"
+    a: "2"
+  namespace: default
+spec:
+  ports:
+    - port: 80
+      name: http
+apiVersion: v1
+`)
+
+	var buf bytes.Buffer
+	err = storage.ExportTo(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.HasPrefix(buf.String(), "apiVersion: v1\n"), true)
+}